@@ -0,0 +1,719 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/forms"
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// The API under /api/v1 plays the same games as the HTML pages with
+// JSON bodies. Creating or joining a game returns a token that the
+// player sends as "Authorization: Bearer <token>" on later requests.
+
+// contextKeyPlayerID holds the ID of the player the
+// request's token belongs to, set by requireToken
+const contextKeyPlayerID = contextKey("playerID")
+
+// apiNewPlayer is the body of a request that creates or joins a
+// game. Fleet maps the ship fields of the ruleset to squares in the
+// notation of the forms, as in "31,32,33". AutoPlace places the fleet
+// at random instead. Ruleset, Size, VsComputer, Level, Private, Clock
+// and AutoFire are only read when creating a game.
+type apiNewPlayer struct {
+	Username   string            `json:"username"`
+	Fleet      map[string]string `json:"fleet"`
+	AutoPlace  bool              `json:"auto_place"`
+	Ruleset    string            `json:"ruleset"`
+	Size       int               `json:"size"`
+	VsComputer bool              `json:"vs_computer"`
+	Level      string            `json:"level"`
+	Private    bool              `json:"private"`
+	Clock      string            `json:"clock"`
+	AutoFire   bool              `json:"auto_fire"`
+}
+
+// validateRules checks the ruleset and board size of the request,
+// which must be known before a fleet can be placed
+func (req *apiNewPlayer) validateRules() *forms.Form {
+	form := forms.New(url.Values{})
+	if req.Ruleset != "" {
+		form.Set("ruleset", req.Ruleset)
+		if models.LookupRuleset(req.Ruleset).Name != req.Ruleset {
+			form.Errors.Add("ruleset", "This field is invalid")
+		}
+	}
+	if req.Size != 0 {
+		form.Set("size", strconv.Itoa(req.Size))
+		if !models.ValidSize(req.Size) {
+			form.Errors.Add("size", "This field is invalid")
+		}
+	}
+	return form
+}
+
+// values gives the request as the fields of the new game form
+func (req *apiNewPlayer) values(rules models.Ruleset) (url.Values, error) {
+	values := url.Values{}
+	values.Set("username", req.Username)
+	for field, posns := range req.Fleet {
+		values.Set(field, posns)
+	}
+	if req.AutoPlace {
+		fleet, err := models.RandomFleet(rules)
+		if err != nil {
+			return nil, err
+		}
+		for field, posns := range fleet {
+			values[field] = posns
+		}
+	}
+	if req.Ruleset != "" {
+		values.Set("ruleset", req.Ruleset)
+	}
+	if req.Size != 0 {
+		values.Set("size", strconv.Itoa(req.Size))
+	}
+	if req.VsComputer {
+		values.Set("vs_computer", "on")
+	}
+	values.Set("level", req.Level)
+	if req.Private {
+		values.Set("private", "on")
+	}
+	if req.Clock != "" {
+		values.Set("clock", req.Clock)
+	}
+	if req.AutoFire {
+		values.Set("timeout", "autofire")
+	}
+	return values, nil
+}
+
+type apiFire struct {
+	Square string `json:"square"`
+}
+
+// apiRematch is the body of a request for a rematch. SameFleet keeps
+// the fleet as placed, or else it is placed at random. BestOf is the
+// length of the series if the rematch starts a new one.
+type apiRematch struct {
+	SameFleet bool `json:"same_fleet"`
+	BestOf    int  `json:"best_of"`
+}
+
+type apiChat struct {
+	Text string `json:"text"`
+}
+
+type apiJoined struct {
+	GameID   string `json:"game_id"`
+	PlayerID string `json:"player_id"`
+	Token    string `json:"token"`
+}
+
+type apiShip struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+	Size  int    `json:"size"`
+}
+
+type apiRules struct {
+	Name           string    `json:"name"`
+	Size           int       `json:"size"`
+	Ships          []apiShip `json:"ships"`
+	ExtraShotOnHit bool      `json:"extra_shot_on_hit"`
+}
+
+type apiShot struct {
+	Square   string   `json:"square"`
+	Outcome  string   `json:"outcome"`
+	Class    string   `json:"class,omitempty"`
+	Wreck    []string `json:"wreck,omitempty"`
+	GameOver bool     `json:"game_over"`
+}
+
+type apiMove struct {
+	Turn   int       `json:"turn"`
+	Player string    `json:"player"`
+	Time   time.Time `json:"time"`
+	apiShot
+}
+
+type apiChatMessage struct {
+	Player string    `json:"player"`
+	Mine   bool      `json:"mine"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// apiClock is the time control of a game and its clocks, in seconds.
+// YourClock and OpponentClock are only given with a game clock.
+type apiClock struct {
+	Name          string   `json:"name"`
+	AutoFire      bool     `json:"auto_fire"`
+	TimeLeft      float64  `json:"time_left"` // for the player to play to fire
+	YourClock     *float64 `json:"your_clock,omitempty"`
+	OpponentClock *float64 `json:"opponent_clock,omitempty"`
+}
+
+// apiGame is the state of a game as seen by one of its players.
+// Board has "water", "ship" or "hit" for each square of the player's
+// board and Shots "unknown", "miss" or "hit" for each square of the
+// opponent's.
+type apiGame struct {
+	ID       string           `json:"id"`
+	Status   string           `json:"status"`
+	Ranked   bool             `json:"ranked"`
+	Rules    apiRules         `json:"rules"`
+	NickName string           `json:"nickname"`
+	Opponent string           `json:"opponent,omitempty"`
+	YourTurn bool             `json:"your_turn"`
+	Board    [][]string       `json:"board"`
+	Shots    [][]string       `json:"shots"`
+	Messages []string         `json:"messages"`
+	Moves    []apiMove        `json:"moves"`
+	Chat     []apiChatMessage `json:"chat"`
+	Muted    bool             `json:"chat_muted"`
+	Series   *apiSeries       `json:"series,omitempty"`
+	Clock    *apiClock        `json:"clock,omitempty"`
+	Forfeit  string           `json:"forfeited_by,omitempty"` // nickname of the player who lost by forfeit
+	NextGame string           `json:"next_game_id,omitempty"` // the rematch, once both players asked for it
+}
+
+// apiSeries is the score of the series of rematches a game is part of
+type apiSeries struct {
+	BestOf   int `json:"best_of,omitempty"`
+	Wins     int `json:"wins"`
+	Opponent int `json:"opponent_wins"`
+}
+
+// apiRanked is a player of the leaderboard
+type apiRanked struct {
+	Rank       int     `json:"rank"`
+	Name       string  `json:"name"`
+	Rating     int     `json:"rating"`
+	Games      int     `json:"games"`
+	Wins       int     `json:"wins"`
+	Losses     int     `json:"losses"`
+	Accuracy   float64 `json:"accuracy"`     // percentage of shots that hit
+	TurnsToWin float64 `json:"turns_to_win"` // average shots fired in the games won
+	ShipsLost  int     `json:"ships_lost"`
+}
+
+type apiShotReply struct {
+	Shot apiShot `json:"shot"`
+	Game apiGame `json:"game"`
+}
+
+type apiErrorReply struct {
+	Error  string              `json:"error"`
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+var apiStatus = map[int]string{
+	models.GameStarting: "waiting",
+	models.GamePlaying:  "playing",
+	models.GameEnded:    "ended",
+}
+
+func newAPIShot(result models.ShotResult) apiShot {
+	shot := apiShot{
+		Square:   result.Square(),
+		Outcome:  result.Outcome.String(),
+		Class:    result.Class,
+		GameOver: result.GameOver,
+	}
+	for _, square := range result.Wreck {
+		shot.Wreck = append(shot.Wreck, square.String())
+	}
+	return shot
+}
+
+// newAPIGame gives the state of the game seen by playerID.
+// The caller must hold the game's Mu.
+func newAPIGame(pgame *models.Game, playerID string) apiGame {
+	pplayer := pgame.Players[playerID]
+	state := apiGame{
+		ID:       pgame.ID,
+		Status:   apiStatus[pgame.Status],
+		Ranked:   pgame.Ranked,
+		NickName: pplayer.NickName,
+		YourTurn: pgame.Status == models.GamePlaying && pgame.NextToPlay == playerID,
+		Board:    make([][]string, len(pplayer.Board)),
+		Shots:    make([][]string, len(pplayer.ShotsBoard)),
+		Messages: append([]string{}, pplayer.StatusMsgs...),
+		Moves:    []apiMove{},
+		Chat:     []apiChatMessage{},
+		Muted:    pgame.ChatMuted,
+		Rules: apiRules{
+			Name:           pgame.Rules.Name,
+			Size:           pgame.Rules.Size,
+			ExtraShotOnHit: pgame.Rules.ExtraShotOnHit,
+		},
+	}
+	for _, ship := range pgame.Rules.Ships {
+		state.Rules.Ships = append(state.Rules.Ships, apiShip{ship.Name, ship.Field, ship.Size})
+	}
+	if popponent, ok := pgame.Players[pplayer.OpponentID]; ok {
+		state.Opponent = popponent.NickName
+	}
+	if pgame.PrevID != "" {
+		state.Series = &apiSeries{
+			BestOf:   pgame.BestOf,
+			Wins:     pgame.Wins[playerID],
+			Opponent: pgame.Wins[pplayer.OpponentID],
+		}
+	}
+	state.NextGame = pgame.NextID
+	if pgame.Clock.Limited() {
+		now := time.Now()
+		left, _ := pgame.TimeLeft(now)
+		state.Clock = &apiClock{
+			Name:     pgame.Clock.Name,
+			AutoFire: pgame.Clock.AutoFire,
+			TimeLeft: left.Seconds(),
+		}
+		if pgame.Clock.PerGame > 0 {
+			mine := pgame.GameClock(playerID, now).Seconds()
+			theirs := pgame.GameClock(pplayer.OpponentID, now).Seconds()
+			state.Clock.YourClock, state.Clock.OpponentClock = &mine, &theirs
+		}
+	}
+	if pforfeited, ok := pgame.Players[pgame.Forfeited]; ok {
+		state.Forfeit = pforfeited.NickName
+	}
+	for _, msg := range pgame.Chat {
+		state.Chat = append(state.Chat, apiChatMessage{
+			Player: msg.NickName,
+			Mine:   msg.PlayerID == playerID,
+			Text:   msg.Text,
+			Time:   msg.Time,
+		})
+	}
+	for row, squares := range pplayer.Board {
+		state.Board[row] = make([]string, len(squares))
+		for col, square := range squares {
+			switch {
+			case square == "":
+				state.Board[row][col] = "water"
+			case strings.HasSuffix(square, "_fire"):
+				state.Board[row][col] = "hit"
+			default:
+				state.Board[row][col] = "ship"
+			}
+		}
+	}
+	for row, squares := range pplayer.ShotsBoard {
+		state.Shots[row] = make([]string, len(squares))
+		for col, square := range squares {
+			switch square {
+			case "":
+				state.Shots[row][col] = "unknown"
+			case "splash":
+				state.Shots[row][col] = "miss"
+			default:
+				state.Shots[row][col] = "hit"
+			}
+		}
+	}
+	for _, move := range pgame.Moves() {
+		state.Moves = append(state.Moves, apiMove{
+			Turn:    move.Turn,
+			Player:  move.NickName,
+			Time:    move.Time,
+			apiShot: newAPIShot(move.ShotResult),
+		})
+	}
+	return state
+}
+
+func (app *application) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// apiError sends an error reply with the given status code and the
+// validation errors of the form, if any.
+func (app *application) apiError(w http.ResponseWriter, status int, msg string, form *forms.Form) {
+	reply := apiErrorReply{Error: msg}
+	if form != nil {
+		reply.Fields = form.Errors
+	}
+	app.writeJSON(w, status, reply)
+}
+
+// apiServerError is the serverError of the API
+func (app *application) apiServerError(w http.ResponseWriter, err error) {
+	app.errorLog.Output(2, err.Error())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `{"error":%q}`, http.StatusText(http.StatusInternalServerError))
+}
+
+// decodeJSON reads the JSON body of the request into v, replying
+// 400 Bad Request and returning false if it cannot.
+func (app *application) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		app.apiError(w, http.StatusBadRequest, "request body is not valid JSON: "+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// requireToken lets through only requests bearing the token of a
+// player of the game, whose ID it adds to the request context.
+func (app *application) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		playerID := ""
+		err := app.games.View(r.URL.Query().Get(":gameid"), func(pgame *models.Game) error {
+			for id, pplayer := range pgame.Players {
+				if pplayer.Token != "" && subtle.ConstantTimeCompare([]byte(pplayer.Token), []byte(token)) == 1 {
+					playerID = id
+				}
+			}
+			return nil
+		})
+		if err == models.ErrNoGame {
+			app.apiError(w, http.StatusNotFound, "no such game", nil)
+			return
+		}
+		if err != nil {
+			app.apiServerError(w, err)
+			return
+		}
+		if playerID == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.apiError(w, http.StatusUnauthorized, "missing or invalid player token", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyPlayerID, playerID)))
+	})
+}
+
+func (app *application) apiCreateGame(w http.ResponseWriter, r *http.Request) {
+	var req apiNewPlayer
+	if !app.decodeJSON(w, r, &req) {
+		return
+	}
+	if app.activeGames() >= app.cfg.maxGames {
+		app.apiError(w, http.StatusServiceUnavailable, "too many games running, try later", nil)
+		return
+	}
+
+	// the fleet is placed on the board of the ruleset and size, so
+	// they are checked first
+	form := req.validateRules()
+	if !form.Valid() {
+		app.apiError(w, http.StatusUnprocessableEntity, "invalid game", form)
+		return
+	}
+	rules := models.LookupRuleset(req.Ruleset)
+	if req.Size != 0 {
+		rules.Size = req.Size
+	}
+	values, err := req.values(rules)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	form = forms.New(values)
+	validateNewGame(form, rules)
+	app.reserveNames(form, "")
+	if !form.Valid() {
+		app.apiError(w, http.StatusUnprocessableEntity, "invalid game", form)
+		return
+	}
+
+	pgame, playerID, err := app.newGame(form, rules, "")
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/games/%s", pgame.ID))
+	app.writeJSON(w, http.StatusCreated, apiJoined{
+		GameID:   pgame.ID,
+		PlayerID: playerID,
+		Token:    pgame.Players[playerID].Token,
+	})
+}
+
+func (app *application) apiJoinGame(w http.ResponseWriter, r *http.Request) {
+	var req apiNewPlayer
+	if !app.decodeJSON(w, r, &req) {
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	var rules models.Ruleset
+	full, ranked := false, false
+	err := app.games.View(gameID, func(pgame *models.Game) error {
+		rules = pgame.Rules
+		full = !pgame.Joinable()
+		ranked = pgame.Ranked
+		return nil
+	})
+	if err == models.ErrNoGame {
+		app.apiError(w, http.StatusNotFound, "no such game", nil)
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	if full {
+		app.apiError(w, http.StatusConflict, errGameFull.Error(), nil)
+		return
+	}
+	// the API has no accounts
+	if ranked {
+		app.apiError(w, http.StatusForbidden, "ranked games are joined from the web by players with accounts", nil)
+		return
+	}
+
+	form := req.validateRules()
+	if !form.Valid() {
+		app.apiError(w, http.StatusUnprocessableEntity, "invalid player", form)
+		return
+	}
+	values, err := req.values(rules)
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	form = forms.New(values)
+	form.ValidateNewGameForm(rules)
+	app.reserveNames(form, "")
+	if !form.Valid() {
+		app.apiError(w, http.StatusUnprocessableEntity, "invalid player", form)
+		return
+	}
+
+	pplayer2, err := app.addPlayer(gameID, form, rules, "")
+	if err == errGameFull {
+		app.apiError(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/games/%s", gameID))
+	app.writeJSON(w, http.StatusCreated, apiJoined{
+		GameID:   gameID,
+		PlayerID: pplayer2.ID,
+		Token:    pplayer2.Token,
+	})
+}
+
+func (app *application) apiGame(w http.ResponseWriter, r *http.Request) {
+	playerID := r.Context().Value(contextKeyPlayerID).(string)
+	var state apiGame
+	err := app.games.View(r.URL.Query().Get(":gameid"), func(pgame *models.Game) error {
+		state = newAPIGame(pgame, playerID)
+		return nil
+	})
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, state)
+}
+
+func (app *application) apiFire(w http.ResponseWriter, r *http.Request) {
+	var req apiFire
+	if !app.decodeJSON(w, r, &req) {
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := r.Context().Value(contextKeyPlayerID).(string)
+	form := forms.New(url.Values{"target_pos": []string{req.Square}})
+	result, err := app.fire(gameID, playerID, form)
+	switch err {
+	case nil:
+	case errInvalidShot:
+		form.Errors["square"] = form.Errors["target_pos"]
+		delete(form.Errors, "target_pos")
+		app.apiError(w, http.StatusUnprocessableEntity, err.Error(), form)
+		return
+	case models.ErrRepeatedShot:
+		app.apiError(w, http.StatusUnprocessableEntity, strings.TrimPrefix(err.Error(), "models: "), nil)
+		return
+	case models.ErrNotYourTurn, models.ErrGameNotActive:
+		app.apiError(w, http.StatusConflict, strings.TrimPrefix(err.Error(), "models: "), nil)
+		return
+	default:
+		app.apiServerError(w, err)
+		return
+	}
+
+	reply := apiShotReply{Shot: newAPIShot(result)}
+	err = app.games.View(gameID, func(pgame *models.Game) error {
+		reply.Game = newAPIGame(pgame, playerID)
+		return nil
+	})
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, reply)
+}
+
+func (app *application) apiChat(w http.ResponseWriter, r *http.Request) {
+	var req apiChat
+	if !app.decodeJSON(w, r, &req) {
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := r.Context().Value(contextKeyPlayerID).(string)
+	err := app.chat(gameID, playerID, req.Text)
+	switch err {
+	case nil:
+	case models.ErrChatEmpty, models.ErrChatTooLong:
+		app.apiError(w, http.StatusUnprocessableEntity, strings.TrimPrefix(err.Error(), "models: "), nil)
+		return
+	case models.ErrChatMuted, models.ErrNoSuchPlayer:
+		app.apiError(w, http.StatusConflict, strings.TrimPrefix(err.Error(), "models: "), nil)
+		return
+	default:
+		app.apiServerError(w, err)
+		return
+	}
+
+	var state apiGame
+	err = app.games.View(gameID, func(pgame *models.Game) error {
+		state = newAPIGame(pgame, playerID)
+		return nil
+	})
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusCreated, state)
+}
+
+func (app *application) apiResign(w http.ResponseWriter, r *http.Request) {
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := r.Context().Value(contextKeyPlayerID).(string)
+	err := app.resign(gameID, playerID)
+	switch err {
+	case nil:
+	case models.ErrGameNotActive:
+		app.apiError(w, http.StatusConflict, strings.TrimPrefix(err.Error(), "models: "), nil)
+		return
+	default:
+		app.apiServerError(w, err)
+		return
+	}
+
+	var state apiGame
+	err = app.games.View(gameID, func(pgame *models.Game) error {
+		state = newAPIGame(pgame, playerID)
+		return nil
+	})
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusOK, state)
+}
+
+func (app *application) apiRematch(w http.ResponseWriter, r *http.Request) {
+	var req apiRematch
+	if !app.decodeJSON(w, r, &req) {
+		return
+	}
+	form := forms.New(url.Values{"best_of": []string{strconv.Itoa(req.BestOf)}})
+	lengths := []string{}
+	for _, length := range models.SeriesLengths {
+		lengths = append(lengths, strconv.Itoa(length))
+	}
+	form.PermittedValues("best_of", lengths...)
+	if !form.Valid() {
+		app.apiError(w, http.StatusUnprocessableEntity, "invalid rematch", form)
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := r.Context().Value(contextKeyPlayerID).(string)
+	nextID, err := app.rematch(gameID, playerID, models.RematchRequest{
+		SameFleet: req.SameFleet,
+		BestOf:    req.BestOf,
+	})
+	switch err {
+	case nil:
+	case models.ErrGameNotOver:
+		app.apiError(w, http.StatusConflict, strings.TrimPrefix(err.Error(), "models: "), nil)
+		return
+	case models.ErrNoSuchPlayer:
+		app.apiError(w, http.StatusConflict, "opponent has left the game", nil)
+		return
+	default:
+		app.apiServerError(w, err)
+		return
+	}
+
+	if nextID != "" {
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/games/%s", nextID))
+		app.writeJSON(w, http.StatusCreated, apiJoined{
+			GameID:   nextID,
+			PlayerID: playerID,
+			Token:    strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		})
+		return
+	}
+	var state apiGame
+	err = app.games.View(gameID, func(pgame *models.Game) error {
+		state = newAPIGame(pgame, playerID)
+		return nil
+	})
+	if err != nil {
+		app.apiServerError(w, err)
+		return
+	}
+	app.writeJSON(w, http.StatusAccepted, state)
+}
+
+func (app *application) apiLeaderboard(w http.ResponseWriter, r *http.Request) {
+	by := r.URL.Query().Get("by")
+	if by != "" && by != "wins" && by != "rating" {
+		app.apiError(w, http.StatusBadRequest, `by must be "wins" or "rating"`, nil)
+		return
+	}
+	ranked := []apiRanked{}
+	for i, s := range app.leaderboard(by) {
+		ranked = append(ranked, apiRanked{
+			Rank:       i + 1,
+			Name:       s.Name,
+			Rating:     s.Rating,
+			Games:      s.Games(),
+			Wins:       s.Wins,
+			Losses:     s.Losses,
+			Accuracy:   math.Round(10*s.Accuracy()) / 10,
+			TurnsToWin: math.Round(10*s.TurnsToWin()) / 10,
+			ShipsLost:  s.ShipsLost,
+		})
+	}
+	app.writeJSON(w, http.StatusOK, ranked)
+}
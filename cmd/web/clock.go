@@ -0,0 +1,84 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/ai"
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// armClock sets the timer of the game to go off when the player to
+// play runs out of time, replacing the timer of the previous turn.
+// It is called whenever the turn may have changed. The caller must
+// hold the game's Mu.
+func (app *application) armClock(pgame *models.Game) {
+	if pgame.Timer != nil {
+		pgame.Timer.Stop()
+		pgame.Timer = nil
+	}
+	left, ok := pgame.TimeLeft(time.Now())
+	if !ok {
+		return
+	}
+	gameID := pgame.ID
+	pgame.Timer = time.AfterFunc(left, func() {
+		app.clockRanOut(gameID)
+	})
+}
+
+// clockRanOut ends the turn of the player to play, who has run out of
+// time: the player forfeits the game, or a shot is fired for the player
+// at random if the game's time control says so
+func (app *application) clockRanOut(gameID string) {
+	err := app.games.Update(gameID, func(pgame *models.Game) error {
+		left, ok := pgame.TimeLeft(time.Now())
+		if !ok {
+			return nil
+		}
+		if left > 0 {
+			// a timer stopped too late, after the turn changed
+			app.armClock(pgame)
+			return nil
+		}
+
+		pplayer := pgame.Players[pgame.NextToPlay]
+		if !pgame.Clock.AutoFire {
+			return app.forfeit(pgame, pplayer.ID, forfeitTime)
+		}
+		err := autoFire(pgame, pplayer, pgame.Players[pplayer.OpponentID])
+		if err != nil {
+			return err
+		}
+		app.armClock(pgame)
+		if pgame.Status == models.GameEnded {
+			app.gameOver(pgame)
+		}
+		return nil
+	})
+	if err == models.ErrNoGame {
+		// deleted before its clock ran out
+		return
+	}
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+}
+
+// autoFire fires a shot at random for a player out of time, and lets
+// a computer opponent reply. The caller must hold the game's Mu.
+func autoFire(pgame *models.Game, pplayer, popponent *models.Player) error {
+	result, err := pgame.Fire(pplayer.ID, ai.Random{}.NextShot(ai.NewKnowledge(pplayer, popponent)))
+	if err != nil {
+		return err
+	}
+	pplayer.StatusMsgs = []string{"You ran out of time. A shot was fired for you at random."}
+	popponent.StatusMsgs = popponent.StatusMsgs[:0]
+	reportShot(pplayer, popponent, result, pgame.NextToPlay == pplayer.ID)
+	fired := []firedShot{{pplayer, pplayer.Shots[len(pplayer.Shots)-1]}}
+	replies, err := botTurn(pgame, popponent)
+	if err != nil {
+		return err
+	}
+	publishShots(pgame, append(fired, replies...))
+	return nil
+}
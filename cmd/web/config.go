@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// config is the configuration of the server. Each setting is taken
+// from its command-line flag, or else from its environment variable,
+// or else from the config file, or else it keeps its default. The
+// variable of a flag is its name in capitals after BTLSHIP_, with
+// dashes as underscores: -max-games is BTLSHIP_MAX_GAMES. The config
+// file, named by -config or BTLSHIP_CONFIG, holds name = value lines
+// with the names of the flags, and # comments. The secret has no flag,
+// as the command line of a process can be read by anyone on the
+// machine: it is taken from BTLSHIP_SECRET, or else from the secret
+// setting of the config file.
+type config struct {
+	addr         string
+	secret       string // key of the session cookies
+	randomSecret bool   // no secret was set, and a random one is used
+	dataDir      string // games, with the replays in dataDir/replays, the accounts in dataDir/users and their statistics in dataDir/stats
+	htmlDir      string
+	staticDir    string
+	infoLog      string
+	errorLog     string
+	maxGames     int
+	waitingTTL   time.Duration
+	playingTTL   time.Duration
+	endedTTL     time.Duration
+	abandonGrace time.Duration // how long a player may be gone from a game in progress before losing it
+}
+
+// envPrefix starts the names of the environment variables of the settings
+const envPrefix = "BTLSHIP_"
+
+// loadConfig reads the configuration from the command-line arguments,
+// the environment and the config file, and validates it
+func loadConfig(args []string) (*config, error) {
+	cfg := &config{}
+	fs := flag.NewFlagSet("web", flag.ExitOnError)
+	path := fs.String("config", "", "optional `file` of name = value settings")
+	fs.StringVar(&cfg.addr, "addr", ":8000", "HTTP network address")
+	fs.StringVar(&cfg.dataDir, "data", "./data", "`directory` where the games, replays, accounts and statistics are saved")
+	fs.StringVar(&cfg.htmlDir, "html", "./ui/html", "`directory` of the page templates")
+	fs.StringVar(&cfg.staticDir, "static", "./ui/static", "`directory` of the static files")
+	fs.StringVar(&cfg.infoLog, "info-log", "info.log", "`file` the information log is appended to")
+	fs.StringVar(&cfg.errorLog, "error-log", "err.log", "`file` the error log is appended to")
+	fs.IntVar(&cfg.maxGames, "max-games", 5, "number of games waiting or in progress at which new games are refused")
+	fs.DurationVar(&cfg.waitingTTL, "waiting-ttl", time.Hour, "how long a game waiting for a second player may stay idle")
+	fs.DurationVar(&cfg.playingTTL, "playing-ttl", 5*time.Hour, "how long a game in progress may stay idle")
+	fs.DurationVar(&cfg.endedTTL, "ended-ttl", time.Hour, "how long an ended game may stay idle")
+	fs.DurationVar(&cfg.abandonGrace, "abandon", 2*time.Minute, "how long a player may be gone from a game in progress before losing it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nThe 32-byte key of the session cookies is read from %s or from the secret\n"+
+			"setting of the config file. Without one a random key is used, which logs\n"+
+			"everyone out when the server restarts.\n", envName("secret"))
+	}
+	fs.Parse(args)
+
+	// the flags given on the command line take precedence
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	if v, ok := os.LookupEnv(envName("config")); ok && !given["config"] {
+		*path = v
+	}
+	settings := map[string]string{}
+	if *path != "" {
+		var err error
+		settings, err = readConfigFile(*path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name := range settings {
+		if name == "config" || name != "secret" && fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("%s: unknown setting %q", *path, name)
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || f.Name == "config" {
+			return
+		}
+		source := envName(f.Name)
+		value, ok := os.LookupEnv(source)
+		if !ok {
+			source = *path
+			value, ok = settings[f.Name]
+		}
+		if !ok {
+			return
+		}
+		if e := fs.Set(f.Name, value); e != nil {
+			err = fmt.Errorf("%s: invalid value %q for %s: %v", source, value, f.Name, e)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg.secret = settings["secret"]
+	if v, ok := os.LookupEnv(envName("secret")); ok {
+		cfg.secret = v
+	}
+
+	err = cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.secret == "" {
+		key := make([]byte, 32)
+		_, err = rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.secret = string(key)
+		cfg.randomSecret = true
+	}
+	return cfg, nil
+}
+
+// validate checks that the settings make sense
+func (cfg *config) validate() error {
+	switch {
+	case cfg.addr == "":
+		return errors.New("config: addr is empty")
+	case cfg.secret != "" && len(cfg.secret) != 32:
+		return fmt.Errorf("config: secret is %d bytes long instead of 32", len(cfg.secret))
+	case cfg.dataDir == "", cfg.htmlDir == "", cfg.staticDir == "":
+		return errors.New("config: data, html and static must name directories")
+	case cfg.infoLog == "", cfg.errorLog == "":
+		return errors.New("config: info-log and error-log must name files")
+	case cfg.maxGames < 1:
+		return fmt.Errorf("config: max-games is %d, and must be at least 1", cfg.maxGames)
+	case cfg.waitingTTL <= 0, cfg.playingTTL <= 0, cfg.endedTTL <= 0:
+		return errors.New("config: waiting-ttl, playing-ttl and ended-ttl must be positive")
+	case cfg.abandonGrace <= 0:
+		return errors.New("config: abandon must be positive")
+	}
+	for _, dir := range []string{cfg.htmlDir, cfg.staticDir} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("config: %v", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("config: %s is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// envName returns the name of the environment variable of a setting
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// readConfigFile reads the name = value lines of a config file. Blank
+// lines and lines starting with # are skipped.
+func readConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected name = value", path, n)
+		}
+		settings[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return settings, scanner.Err()
+}
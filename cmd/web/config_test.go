@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testDirs are the flags of the directories of the templates and
+// static files, seen from the directory of the tests
+var testDirs = []string{"-html", "../../ui/html", "-static", "../../ui/static"}
+
+// writeConfig writes a config file, which the caller removes
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "btlship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		os.Remove(f.Name())
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+// setenv sets the environment variables and returns a function that
+// unsets them
+func setenv(vars map[string]string) func() {
+	for name, value := range vars {
+		os.Setenv(name, value)
+	}
+	return func() {
+		for name := range vars {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	file := writeConfig(t, "# settings\nmax-games = 10\nwaiting-ttl = 10m\nplaying-ttl = 3h\n")
+	defer os.Remove(file)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want int
+	}{
+		{"default", nil, nil, 5},
+		{"file", []string{"-config", file}, nil, 10},
+		{"file named by the environment", nil, map[string]string{"BTLSHIP_CONFIG": file}, 10},
+		{"environment over file", []string{"-config", file}, map[string]string{"BTLSHIP_MAX_GAMES": "20"}, 20},
+		{"flag over environment", []string{"-config", file, "-max-games", "30"}, map[string]string{"BTLSHIP_MAX_GAMES": "20"}, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setenv(tt.env)()
+			cfg, err := loadConfig(append(tt.args, testDirs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.maxGames != tt.want {
+				t.Errorf("got max-games %d; want %d", cfg.maxGames, tt.want)
+			}
+		})
+	}
+
+	// the settings that are not overridden still come from the file
+	defer setenv(map[string]string{"BTLSHIP_PLAYING_TTL": "4h"})()
+	cfg, err := loadConfig(append([]string{"-config", file, "-waiting-ttl", "20m"}, testDirs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.waitingTTL.String() != "20m0s" || cfg.playingTTL.String() != "4h0m0s" || cfg.maxGames != 10 {
+		t.Errorf("got waiting-ttl %s, playing-ttl %s, max-games %d", cfg.waitingTTL, cfg.playingTTL, cfg.maxGames)
+	}
+}
+
+func TestLoadConfigSecret(t *testing.T) {
+	fileSecret := strings.Repeat("f", 32)
+	envSecret := strings.Repeat("e", 32)
+	file := writeConfig(t, "secret = "+fileSecret+"\n")
+	defer os.Remove(file)
+	short := writeConfig(t, "secret = too short\n")
+	defer os.Remove(short)
+
+	tests := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		want   string
+		random bool
+		err    bool
+	}{
+		{"none", nil, nil, "", true, false},
+		{"file", []string{"-config", file}, nil, fileSecret, false, false},
+		{"environment", nil, map[string]string{"BTLSHIP_SECRET": envSecret}, envSecret, false, false},
+		{"environment over file", []string{"-config", file}, map[string]string{"BTLSHIP_SECRET": envSecret}, envSecret, false, false},
+		{"short in file", []string{"-config", short}, nil, "", false, true},
+		{"short in environment", nil, map[string]string{"BTLSHIP_SECRET": "0123456789"}, "", false, true},
+		{"too long", nil, map[string]string{"BTLSHIP_SECRET": envSecret + "e"}, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setenv(tt.env)()
+			cfg, err := loadConfig(append(tt.args, testDirs...))
+			if tt.err {
+				if err == nil {
+					t.Errorf("accepted a secret of %d bytes", len(cfg.secret))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.randomSecret != tt.random || len(cfg.secret) != 32 {
+				t.Errorf("got a secret of %d bytes, random %t", len(cfg.secret), cfg.randomSecret)
+			}
+			if !tt.random && cfg.secret != tt.want {
+				t.Errorf("got secret %q; want %q", cfg.secret, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigUnknownSetting(t *testing.T) {
+	file := writeConfig(t, "max-gams = 10\n")
+	defer os.Remove(file)
+	_, err := loadConfig(append([]string{"-config", file}, testDirs...))
+	if err == nil || !strings.Contains(err.Error(), "max-gams") {
+		t.Errorf("got %v; want an unknown setting error", err)
+	}
+}
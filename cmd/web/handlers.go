@@ -1,12 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
-	"strconv"
-	"strings"
+	"net/url"
 	"time"
 
+	"github.com/rjpgt/battleship/pkg/ai"
 	"github.com/rjpgt/battleship/pkg/forms"
 	"github.com/rjpgt/battleship/pkg/models"
 )
@@ -15,18 +17,25 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 	if app.session.Exists(r, "gameID") {
 		gameID := app.session.GetString(r, "gameID")
 		http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+	} else if app.session.Exists(r, "seekerID") {
+		http.Redirect(w, r, "/match", http.StatusSeeOther)
 	} else {
 		http.Redirect(w, r, "/start", http.StatusSeeOther)
 	}
 }
 
 func (app *application) startGameForm(w http.ResponseWriter, r *http.Request) {
-	if len(app.gameModel.Games) == MaxGames {
+	if app.activeGames() >= app.cfg.maxGames {
 		w.Write([]byte("Sorry, too many games right now. Please try after a while."))
 		return
 	}
 	app.render(w, r, "startjoin.page.tmpl", &templateData{
-		Form: forms.New(nil),
+		Clocks:   models.TimeControls,
+		Form:     forms.New(nil),
+		Levels:   ai.Levels,
+		Rules:    models.LookupRuleset(r.URL.Query().Get("ruleset")),
+		Rulesets: models.Rulesets,
+		Sizes:    models.BoardSizes,
 	})
 }
 
@@ -37,57 +46,279 @@ func (app *application) startGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	form := forms.New(r.PostForm)
-	form.ValidateNewGameForm()
+	userID := app.playAs(r)
+	rules := models.LookupRuleset(r.PostForm.Get("ruleset"))
+	rules.Size = boardSize(r.PostForm, rules.Size)
+	placed, err := autoPlace(r, rules)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if placed {
+		app.render(w, r, "startjoin.page.tmpl", &templateData{
+			Clocks:   models.TimeControls,
+			Form:     forms.New(r.PostForm),
+			Levels:   ai.Levels,
+			Rules:    rules,
+			Rulesets: models.Rulesets,
+			Sizes:    models.BoardSizes,
+		})
+		return
+	}
 
+	form := forms.New(r.PostForm)
+	validateNewGame(form, rules)
+	validateRanked(form, userID)
+	validateMatch(form, userID)
+	app.reserveNames(form, userID)
 	if !form.Valid() {
-		app.render(w, r, "startjoin.page.tmpl", &templateData{Form: form})
+		app.render(w, r, "startjoin.page.tmpl", &templateData{
+			Clocks:   models.TimeControls,
+			Form:     form,
+			Levels:   ai.Levels,
+			Rules:    rules,
+			Rulesets: models.Rulesets,
+			Sizes:    models.BoardSizes,
+		})
+		return
+	}
+
+	if form.Get("match") != "" {
+		app.findMatch(w, r, form, rules, userID)
 		return
 	}
 
-	pgame, err := models.NewGame(form.Values)
+	pgame, playerID, err := app.newGame(form, rules, userID)
 	if err != nil {
 		app.serverError(w, err)
 		return
 	}
-	app.gameModel.Games[pgame.ID] = pgame
-	// delete game from gameModel after timeout
-	go app.gameTimeout(pgame.ID)
 	app.session.Put(r, "gameID", pgame.ID)
-	for playerID := range pgame.Players {
-		app.session.Put(r, "playerID", playerID)
-	}
+	app.session.Put(r, "playerID", playerID)
 	http.Redirect(w, r, fmt.Sprintf("/%s", pgame.ID), http.StatusSeeOther)
 }
 
+// findMatch is startGame for a player who asked for an opponent from
+// the matchmaking queue. The player goes to the game if paired at
+// once, or else waits on /match.
+func (app *application) findMatch(w http.ResponseWriter, r *http.Request, form *forms.Form, rules models.Ruleset, userID string) {
+	seekerID, gameID, playerID, err := app.findOpponent(form, rules, userID)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if gameID == "" {
+		// the player leaves the queue if the waiting page never opens
+		time.AfterFunc(MatchGrace, func() {
+			app.queue.CancelIdle(seekerID)
+		})
+		app.session.Put(r, "seekerID", seekerID)
+		http.Redirect(w, r, "/match", http.StatusSeeOther)
+		return
+	}
+	app.session.Put(r, "gameID", gameID)
+	app.session.Put(r, "playerID", playerID)
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+// waitMatch shows the page of a player waiting in the matchmaking
+// queue, and moves the player on to the game once paired
+func (app *application) waitMatch(w http.ResponseWriter, r *http.Request) {
+	seekerID := app.session.GetString(r, "seekerID")
+	seeker, ok := app.queue.Get(seekerID)
+	if !ok {
+		app.session.Remove(r, "seekerID")
+		if seekerID != "" {
+			app.session.Put(r, "flash", "You are no longer waiting for an opponent.")
+		}
+		http.Redirect(w, r, "/start", http.StatusSeeOther)
+		return
+	}
+	if seeker.Failed {
+		app.queue.Forget(seekerID)
+		app.session.Remove(r, "seekerID")
+		app.session.Put(r, "flash", "The game with your opponent could not be made. Please try again.")
+		http.Redirect(w, r, "/start", http.StatusSeeOther)
+		return
+	}
+	if seeker.GameID != "" {
+		app.queue.Forget(seekerID)
+		app.session.Remove(r, "seekerID")
+		app.session.Put(r, "gameID", seeker.GameID)
+		app.session.Put(r, "playerID", seeker.PlayerID)
+		http.Redirect(w, r, fmt.Sprintf("/%s", seeker.GameID), http.StatusSeeOther)
+		return
+	}
+	app.render(w, r, "match.page.tmpl", &templateData{
+		Queued: app.queue.Len(),
+		Seeker: &seeker,
+		Seq:    seeker.Events.Seq(),
+	})
+}
+
+// cancelMatch takes the player out of the matchmaking queue
+func (app *application) cancelMatch(w http.ResponseWriter, r *http.Request) {
+	seekerID := app.session.GetString(r, "seekerID")
+	if !app.queue.Cancel(seekerID) {
+		// paired in the meantime
+		http.Redirect(w, r, "/match", http.StatusSeeOther)
+		return
+	}
+	app.session.Remove(r, "seekerID")
+	http.Redirect(w, r, "/start", http.StatusSeeOther)
+}
+
+// matchSse tells the page of a player waiting in the matchmaking
+// queue when the player is paired. A player who closes the page and
+// does not open it again within MatchGrace leaves the queue.
+func (app *application) matchSse(w http.ResponseWriter, r *http.Request) {
+	seekerID := app.session.GetString(r, "seekerID")
+	seeker, ok := app.queue.Get(seekerID)
+	if !ok {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+	app.queue.PageOpened(seekerID)
+	app.sendEvents(w, r, seeker.Events, seeker.Events.Subscribe, func(ev models.Event) (interface{}, error) {
+		return pageEvent{Seq: ev.Seq, Type: string(ev.Type)}, nil
+	})
+	app.queue.PageClosed(seekerID)
+	// the page may only be reloading
+	time.AfterFunc(MatchGrace, func() {
+		app.queue.CancelIdle(seekerID)
+	})
+}
+
 func (app *application) playGameForm(w http.ResponseWriter, r *http.Request) {
 	gameID := r.URL.Query().Get(":gameid")
-	pgame, _ := app.gameModel.Games[gameID]
 	playerID := app.session.GetString(r, "playerID")
-	pgame.Mu.Lock()
-	defer pgame.Mu.Unlock()
-	pplayer, _ := pgame.Players[playerID]
+	nextID := ""
+	found := false
+	// the page is rendered under the game's Mu, as it reads the game
+	err := app.games.View(gameID, func(pgame *models.Game) error {
+		// both players asked for a rematch, which the page moves on to
+		if pgame.NextID != "" {
+			nextID = pgame.NextID
+			return nil
+		}
+		pplayer, ok := pgame.Players[playerID]
+		if !ok {
+			return nil
+		}
+		found = true
 
-	ptd := &templateData{
-		Player: pplayer,
-		Status: pgame.Status,
+		ptd := &templateData{
+			GameID: gameID,
+			Moves:  pgame.Moves(),
+			Player: pplayer,
+			Status: pgame.Status,
+		}
+
+		// the page shows everything that happened so far
+		// and gets the events that follow over /sse
+		ptd.Seq = pgame.Events.Seq()
+		ptd.Spectators = pgame.Spectators
+		ptd.Watching = pgame.Events.Spectators()
+		ptd.Owner = pgame.Owner == playerID
+		ptd.Ranked = pgame.Ranked
+		ptd.Private = pgame.Private
+		ptd.Clock = newClockData(pgame, playerID)
+		if popponent, ok := pgame.Players[pplayer.OpponentID]; !ok || !popponent.Bot {
+			ptd.Chat = &chatData{
+				Messages:  pgame.Chat,
+				Muted:     pgame.ChatMuted,
+				MaxLength: models.MaxChatLength,
+			}
+		}
+
+		if pgame.Status == models.GameEnded {
+			ptd.Rematch = newRematchData(pgame, playerID)
+		} else {
+			// the fire form is shown or hidden by sse.js as the turn changes
+			ptd.Form = forms.New(nil)
+			ptd.YourTurn = pgame.Status == models.GamePlaying && pgame.NextToPlay == pplayer.ID
+			if popponent, ok := pgame.Players[pplayer.OpponentID]; ok {
+				ptd.Opponent = popponent.NickName
+			}
+		}
+
+		app.render(w, r, "play.page.tmpl", ptd)
+		return nil
+	})
+	if err == models.ErrNoGame {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
+	if nextID != "" {
+		app.session.Put(r, "gameID", nextID)
+		http.Redirect(w, r, fmt.Sprintf("/%s", nextID), http.StatusSeeOther)
+		return
+	}
+	if !found {
+		app.notFound(w)
+	}
+}
 
-	if pgame.Status == 1 && pgame.NextToPlay == pplayer.ID {
-		ptd.Form = forms.New(nil)
-		ptd.GameID = gameID
-		ptd.Opponent = pgame.Players[pplayer.OpponentID].NickName
+func (app *application) rematchGame(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 
-	if pgame.Status == 2 {
-		delete(pgame.Players, playerID)
-		if len(pgame.Players) == 0 {
-			delete(app.gameModel.Games, gameID)
-		}
-		app.session.Destroy(r)
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := app.session.GetString(r, "playerID")
+	nextID, err := app.rematch(gameID, playerID, models.RematchRequest{
+		SameFleet: r.PostForm.Get("fleet") != "auto",
+		BestOf:    seriesLength(r.PostForm),
+	})
+	switch err {
+	case nil:
+	case models.ErrGameNotOver, models.ErrNoSuchPlayer:
+		// the page tells why there is no rematch
+	default:
+		app.serverError(w, err)
+		return
+	}
+	if nextID != "" {
+		app.session.Put(r, "gameID", nextID)
+		gameID = nextID
 	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
 
-	app.render(w, r, "play.page.tmpl", ptd)
+// resignGame ends the game in progress as lost by the player
+func (app *application) resignGame(w http.ResponseWriter, r *http.Request) {
+	gameID := r.URL.Query().Get(":gameid")
+	err := app.resign(gameID, app.session.GetString(r, "playerID"))
+	if err == models.ErrGameNotActive {
+		app.session.Put(r, "flash", "The game is not in progress.")
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+func (app *application) leaveGame(w http.ResponseWriter, r *http.Request) {
+	gameID := r.URL.Query().Get(":gameid")
+	err := app.leave(gameID, app.session.GetString(r, "playerID"))
+	if err == models.ErrGameNotOver {
+		http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	// the player stays logged in
+	app.session.Remove(r, "gameID")
+	app.session.Remove(r, "playerID")
+	http.Redirect(w, r, "/start", http.StatusSeeOther)
 }
 
 func (app *application) handleSse(w http.ResponseWriter, r *http.Request) {
@@ -97,64 +328,443 @@ func (app *application) handleSse(w http.ResponseWriter, r *http.Request) {
 	}
 
 	gameID := app.session.GetString(r, "gameID")
-	pgame, ok := app.gameModel.Games[gameID]
-	if !ok {
+	playerID := app.session.GetString(r, "playerID")
+	ok := false
+	err := app.games.View(gameID, func(pgame *models.Game) error {
+		_, ok = pgame.Players[playerID]
+		return nil
+	})
+	if err != nil || !ok {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
-	playerID := app.session.GetString(r, "playerID")
-	pplayer, ok := pgame.Players[playerID]
-	if !ok {
+	app.stream(w, r, gameID, playerID)
+}
+
+func (app *application) watchSse(w http.ResponseWriter, r *http.Request) {
+	app.stream(w, r, r.URL.Query().Get(":gameid"), "")
+}
+
+// stream sends the events of the game to the page of playerID, or to
+// the page of a spectator if playerID is empty, until the page closes.
+// The page of a player counts as open while it streams.
+func (app *application) stream(w http.ResponseWriter, r *http.Request, gameID, playerID string) {
+	var bus *models.Bus
+	err := app.games.View(gameID, func(pgame *models.Game) error {
+		bus = pgame.Events
+		return nil
+	})
+	if err != nil {
 		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
+	subscribe := bus.Subscribe
+	if playerID == "" {
+		subscribe = bus.Watch
+	} else {
+		app.pageOpened(gameID, playerID)
+		defer app.pageClosed(gameID, playerID)
+	}
+	app.sendEvents(w, r, bus, subscribe, func(ev models.Event) (interface{}, error) {
+		var pev pageEvent
+		err := app.games.View(gameID, func(pgame *models.Game) error {
+			pev = newPageEvent(pgame, playerID, ev)
+			return nil
+		})
+		if err == models.ErrNoGame {
+			// the game was removed since, and the event is all there is
+			return pageEvent{Seq: ev.Seq, Type: string(ev.Type)}, nil
+		}
+		return pev, err
+	})
+}
+
+// sendEvents sends the events of the bus to a page as server sent
+// events until the page closes. subscribe is the bus's Subscribe or
+// Watch, and encode gives the data the page gets for an event.
+func (app *application) sendEvents(w http.ResponseWriter, r *http.Request, bus *models.Bus,
+	subscribe func(after int) ([]models.Event, chan models.Event, bool),
+	encode func(ev models.Event) (interface{}, error)) {
+	// write past the buffered writer of the sessions middleware
+	// so that every event reaches the page as soon as it is sent
+	stream, _ := r.Context().Value(contextKeyWriter).(http.ResponseWriter)
+	flusher, ok := stream.(http.Flusher)
+	if !ok {
+		app.serverError(w, fmt.Errorf("streaming is not supported by %T", stream))
+		return
+	}
+	w = stream
+
+	missed, events, ok := subscribe(lastEventID(r))
+	defer bus.Unsubscribe(events)
+
 	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !ok {
+		// the page is older than the events kept by the bus
+		fmt.Fprintf(w, "data: {\"type\":\"reload\"}\n\n")
+		flusher.Flush()
+		return
+	}
+
+	send := func(ev models.Event) error {
+		v, err := encode(ev)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.Seq, data)
+		return nil
+	}
+	for _, ev := range missed {
+		if send(ev) != nil {
+			return
+		}
+	}
+	flusher.Flush()
 
 	for {
 		select {
 		//request closed
 		case <-r.Context().Done():
-			ticker.Stop()
 			return
 		case <-ticker.C:
-			/*
-				Because of the use of the sessions package
-				which uses a buffered writer this message will not
-				reach the client since we are not returning from this handler
-				here. But it will prevent idletimeout
-			*/
-			fmt.Fprintf(w, "data: %v\n\n", "stay alive")
-			//w.(http.Flusher).Flush()
-		case <-pplayer.MsgChn:
-			fmt.Fprintf(w, "data: %v\n\n", "refresh")
-			ticker.Stop()
-			return
+			// a comment line, ignored by EventSource, that keeps
+			// the connection from timing out
+			fmt.Fprint(w, ": stay alive\n\n")
+			flusher.Flush()
+		case ev, open := <-events:
+			// a closed channel means the page fell behind; it
+			// reconnects and resumes after the last event it got
+			if !open || send(ev) != nil {
+				return
+			}
+			flusher.Flush()
 		}
 	}
 }
 
-func (app *application) joinGameForm(w http.ResponseWriter, r *http.Request) {
+func (app *application) watchGame(w http.ResponseWriter, r *http.Request) {
+	gameID := r.URL.Query().Get(":gameid")
+	nextID := ""
+	err := app.games.View(gameID, func(pgame *models.Game) error {
+		// spectators follow the players to their rematch
+		if pgame.NextID != "" {
+			nextID = pgame.NextID
+			return nil
+		}
+		ptd := &templateData{
+			GameID:   gameID,
+			Moves:    pgame.Moves(),
+			Seq:      pgame.Events.Seq(),
+			Status:   pgame.Status,
+			Watching: pgame.Events.Spectators(),
+		}
+		// the owner's shots first, as seats number the players in events
+		if pplayer, ok := pgame.Players[pgame.Owner]; ok {
+			ptd.Players = append(ptd.Players, pplayer)
+		}
+		for id, pplayer := range pgame.Players {
+			if id != pgame.Owner {
+				ptd.Players = append(ptd.Players, pplayer)
+			}
+		}
+		app.render(w, r, "watch.page.tmpl", ptd)
+		return nil
+	})
+	if err == models.ErrNoGame {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if nextID != "" {
+		http.Redirect(w, r, fmt.Sprintf("/watch/%s", nextID), http.StatusSeeOther)
+	}
+}
+
+func (app *application) allowSpectators(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
 	gameID := r.URL.Query().Get(":gameid")
-	pgame, _ := app.gameModel.Games[gameID]
+	playerID := app.session.GetString(r, "playerID")
+	owner := false
+	err = app.games.Update(gameID, func(pgame *models.Game) error {
+		owner = pgame.Owner == playerID
+		if !owner {
+			return nil
+		}
+		pgame.Spectators = r.PostForm.Get("spectators") == "on"
+		if !pgame.Spectators {
+			pgame.Events.DropSpectators()
+		}
+		return nil
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if !owner {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+func (app *application) sendChat(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	err = app.chat(gameID, app.session.GetString(r, "playerID"), r.PostForm.Get("message"))
+	if msg := chatError(err); msg != "" {
+		app.session.Put(r, "flash", msg)
+	} else if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+// muteChat lets the owner of a game mute the chat, or unmute it
+func (app *application) muteChat(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := app.session.GetString(r, "playerID")
+	owner := false
+	err = app.games.Update(gameID, func(pgame *models.Game) error {
+		owner = pgame.Owner == playerID
+		if !owner {
+			return nil
+		}
+		pgame.ChatMuted = r.PostForm.Get("mute") == "on"
+		pgame.Events.Publish(models.Event{Type: models.EventMuted, PlayerID: playerID})
+		return nil
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if !owner {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+// makePrivate lets the owner of a game take it out of the lobby, or
+// list it again, while it waits for a second player
+func (app *application) makePrivate(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	gameID := r.URL.Query().Get(":gameid")
+	playerID := app.session.GetString(r, "playerID")
+	owner, listed := false, false
+	err = app.games.Update(gameID, func(pgame *models.Game) error {
+		owner = pgame.Owner == playerID
+		if !owner || pgame.Status != models.GameStarting {
+			return nil
+		}
+		listed = pgame.Listed()
+		pgame.Private = r.PostForm.Get("private") == "on"
+		listed = listed || pgame.Listed()
+		return nil
+	})
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if !owner {
+		app.clientError(w, http.StatusForbidden)
+		return
+	}
+	if listed {
+		app.lobbyChanged(gameID)
+	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+func (app *application) replayGame(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(":gameid")
+	replay, err := app.replays.Get(id)
+	if err == models.ErrNoReplay {
+		app.session.Put(r, "flash", "No such replay. Replays are kept for games that have ended.")
+		http.Redirect(w, r, "/start", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	players, err := replay.Fleets()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	results, err := replay.Play()
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
 
 	ptd := &templateData{
-		GameID: gameID,
-		Form:   forms.New(nil),
+		GameID:  replay.ID,
+		Players: players,
+		Replay:  []replayMove{},
+	}
+	for i, result := range results {
+		seat := replay.Shots[i].Player
+		move := replayMove{
+			Seat:     seat,
+			By:       players[seat].NickName,
+			Square:   result.Square(),
+			Outcome:  result.Outcome.String(),
+			Class:    result.Class,
+			GameOver: result.GameOver,
+		}
+		for _, square := range result.Wreck {
+			move.Wreck = append(move.Wreck, square.String())
+		}
+		ptd.Replay = append(ptd.Replay, move)
+	}
+	app.render(w, r, "replay.page.tmpl", ptd)
+}
+
+func (app *application) exportReplay(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(":gameid")
+	replay, err := app.replays.Get(id)
+	if err == models.ErrNoReplay {
+		app.notFound(w)
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	data, err := json.MarshalIndent(replay, "", "  ")
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"battleship-%s.json\"", replay.ID))
+	w.Write(data)
+}
+
+func (app *application) importReplayForm(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "import.page.tmpl", &templateData{Form: forms.New(nil)})
+}
+
+func (app *application) importReplay(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
 
-	// only 1 player in Players at this stage
-	for _, pplayer := range pgame.Players {
-		ptd.Opponent = pplayer.NickName
+	form := forms.New(r.PostForm)
+	var data []byte
+	file, _, err := r.FormFile("replay")
+	if err == nil {
+		data, err = ioutil.ReadAll(file)
+		file.Close()
+	}
+	if err != nil {
+		form.Errors.Add("replay", "Choose a replay file to import")
+		app.render(w, r, "import.page.tmpl", &templateData{Form: form})
+		return
 	}
+
+	replay, err := models.ImportReplay(data)
+	if rerr, ok := err.(*models.ReplayError); ok {
+		form.Errors.Add("replay", rerr.Reason)
+		app.render(w, r, "import.page.tmpl", &templateData{Form: form})
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	// the fleets must also pass the checks of the new game form
+	rules, _ := replay.Rules()
+	for _, rp := range replay.Players {
+		fleet := forms.New(url.Values{"username": []string{rp.NickName}})
+		for field, posns := range rp.Fleet {
+			fleet.Set(field, posns)
+		}
+		fleet.ValidateNewGameForm(rules)
+		for field, msgs := range fleet.Errors {
+			for _, msg := range msgs {
+				form.Errors.Add("replay", fmt.Sprintf("%s, %s: %s", rp.NickName, field, msg))
+			}
+		}
+	}
+	if !form.Valid() {
+		app.render(w, r, "import.page.tmpl", &templateData{Form: form})
+		return
+	}
+
+	err = app.replays.Put(replay)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/replay/%s", replay.ID), http.StatusSeeOther)
+}
+
+func (app *application) joinGameForm(w http.ResponseWriter, r *http.Request) {
+	ptd := app.joinTemplateData(r.URL.Query().Get(":gameid"))
+	ptd.Form = forms.New(nil)
 	app.render(w, r, "startjoin.page.tmpl", ptd)
 }
 
+// joinTemplateData gives the data of the join form that comes
+// from the game being joined
+func (app *application) joinTemplateData(gameID string) *templateData {
+	ptd := &templateData{GameID: gameID}
+	app.games.View(gameID, func(pgame *models.Game) error {
+		// only 1 player in Players at this stage
+		for _, pplayer := range pgame.Players {
+			ptd.Opponent = pplayer.NickName
+		}
+		ptd.Rules = pgame.Rules
+		ptd.Ranked = pgame.Ranked
+		ptd.Clock = newClockData(pgame, "")
+		return nil
+	})
+	return ptd
+}
+
 func (app *application) joinGame(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseForm()
 	if err != nil {
@@ -162,48 +772,50 @@ func (app *application) joinGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID := app.playAs(r)
 	gameID := r.URL.Query().Get(":gameid")
-	pgame, _ := app.gameModel.Games[gameID]
-	pgame.Mu.Lock()
-	defer pgame.Mu.Unlock()
-	var pplayer1 *models.Player
-	// only 1 player in Players at this stage
-	for _, pplayer := range pgame.Players {
-		pplayer1 = pplayer
+	ptd := app.joinTemplateData(gameID)
+	placed, err := autoPlace(r, ptd.Rules)
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+	if placed {
+		ptd.Form = forms.New(r.PostForm)
+		app.render(w, r, "startjoin.page.tmpl", ptd)
+		return
 	}
 
 	form := forms.New(r.PostForm)
-	form.ValidateNewGameForm()
+	form.ValidateNewGameForm(ptd.Rules)
+	if ptd.Ranked && userID == "" {
+		form.Errors.Add("ranked", "Log in to join a ranked game")
+	}
+	app.reserveNames(form, userID)
 	if !form.Valid() {
-		ptd := &templateData{
-			GameID: gameID,
-			Form:   form,
-		}
-		ptd.Opponent = pplayer1.NickName
+		ptd.Form = form
 		app.render(w, r, "startjoin.page.tmpl", ptd)
 		return
 	}
 
-	pplayer2, err := models.NewPlayer(form.Values)
-	if err != nil {
-		app.serverError(w, err)
+	pplayer2, err := app.addPlayer(gameID, form, ptd.Rules, userID)
+	if err == errGameFull {
+		app.session.Put(r, "flash", "Game is full. Start another.")
+		http.Redirect(w, r, "/start", http.StatusSeeOther)
 		return
 	}
-	pplayer1.OpponentID = pplayer2.ID
-	pplayer2.OpponentID = pplayer1.ID
-	pplayer2.StatusMsgs = []string{
-		fmt.Sprintf("Waiting for %s to play.", pplayer1.NickName),
+	if err == errOwnGame {
+		app.session.Put(r, "flash", "You cannot join a game you started. Start another.")
+		http.Redirect(w, r, "/start", http.StatusSeeOther)
+		return
 	}
-	pgame.Players[pplayer2.ID] = pplayer2
-	pplayer1.StatusMsgs = []string{
-		fmt.Sprintf("%s has joined the game", pplayer2.NickName),
-		"It's your turn to play.",
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
-	pplayer1.MsgChn <- "refresh"
-	pgame.Status = 1
-	app.session.Put(r, "gameID", pgame.ID)
+	app.session.Put(r, "gameID", gameID)
 	app.session.Put(r, "playerID", pplayer2.ID)
-	http.Redirect(w, r, fmt.Sprintf("/%s", pgame.ID), http.StatusSeeOther)
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
 }
 
 func (app *application) playGame(w http.ResponseWriter, r *http.Request) {
@@ -214,73 +826,142 @@ func (app *application) playGame(w http.ResponseWriter, r *http.Request) {
 	}
 
 	gameID := r.URL.Query().Get(":gameid")
-	pgame, _ := app.gameModel.Games[gameID]
 	playerID := app.session.GetString(r, "playerID")
-	pgame.Mu.Lock()
-	defer pgame.Mu.Unlock()
+	form := forms.New(r.PostForm)
 
-	if pgame.NextToPlay != playerID {
-		http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+	_, err = app.fire(gameID, playerID, form)
+	switch err {
+	case nil, errInvalidShot, models.ErrRepeatedShot, models.ErrNotYourTurn, models.ErrGameNotActive:
+	default:
+		app.serverError(w, err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+}
+
+func (app *application) signupUserForm(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "signup.page.tmpl", &templateData{
+		Form: forms.New(nil),
+	})
+}
+
+func (app *application) signupUser(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
-	pplayer := pgame.Players[playerID]
 	form := forms.New(r.PostForm)
-	form.ValidateFireForm()
+	form.ValidateSignupForm()
 	if !form.Valid() {
-		pplayer.StatusMsgs = append(pplayer.StatusMsgs, "You have entered an invalid firing position. Try again.")
-		http.Redirect(w, r, fmt.Sprintf("/%s", gameID), http.StatusSeeOther)
+		app.render(w, r, "signup.page.tmpl", &templateData{Form: form})
 		return
 	}
 
-	field := form.Values.Get("target_pos")
-	num, _ := strconv.Atoi(strings.TrimSpace(field))
-	hitPos := [2]int{num / 10, num % 10}
-	hitFlag := false
-	shipDestroyed := ""
-
-	pplayer.StatusMsgs = pplayer.StatusMsgs[:0]
-	popponent := pgame.Players[pplayer.OpponentID]
-	popponent.StatusMsgs = popponent.StatusMsgs[:0]
-outer:
-	for i, pship := range popponent.Ships {
-		for partIndex, shipPart := range pship.Parts {
-			if hitPos == shipPart.Pos {
-				hitFlag = true
-				delete(pship.Parts, partIndex)
-				if len(pship.Parts) == 0 {
-					delete(popponent.Ships, i)
-					shipDestroyed = pship.Class
-				}
-				break outer
-			}
-		}
+	u, err := models.NewUser(form.Get("name"), form.Get("password"))
+	if err != nil {
+		app.serverError(w, err)
+		return
 	}
-	if hitFlag {
-		popponent.Board[hitPos[0]][hitPos[1]] = popponent.Board[hitPos[0]][hitPos[1]] + "_fire"
-		pplayer.ShotsBoard[hitPos[0]][hitPos[1]] = "hit_bomb"
-		pplayer.StatusMsgs = append(pplayer.StatusMsgs, "You have HIT a ship.")
-		popponent.StatusMsgs = append(popponent.StatusMsgs, "You have been hit.")
-		if shipDestroyed != "" {
-			pplayer.StatusMsgs = append(pplayer.StatusMsgs, "You have destroyed a "+shipDestroyed+".")
-			popponent.StatusMsgs = append(popponent.StatusMsgs, "You have lost a "+shipDestroyed+".")
-			if len(popponent.Ships) == 0 {
-				pplayer.StatusMsgs = append(pplayer.StatusMsgs, "You have destroyed all your opponent's ships.", "You are the WINNER!")
-				popponent.StatusMsgs = append(popponent.StatusMsgs, "You have lost  all your ships", "You have lost the game.")
-				pgame.Status = 2
-			}
-		}
-	} else {
-		pplayer.ShotsBoard[hitPos[0]][hitPos[1]] = "splash"
-		pplayer.StatusMsgs = append(pplayer.StatusMsgs, "You missed.")
-		popponent.StatusMsgs = append(popponent.StatusMsgs, fmt.Sprintf("%s has missed. No casualty.", pplayer.NickName))
+	err = app.users.Insert(u)
+	if err == models.ErrDuplicateName {
+		form.Errors.Add("name", "This name is already taken")
+		app.render(w, r, "signup.page.tmpl", &templateData{Form: form})
+		return
 	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "authenticatedUserID", u.ID)
+	app.session.Put(r, "flash", fmt.Sprintf("Welcome, %s. Your games are now played as %s.", u.Name, u.Name))
+	http.Redirect(w, r, "/start", http.StatusSeeOther)
+}
+
+func (app *application) loginUserForm(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "login.page.tmpl", &templateData{
+		Form: forms.New(nil),
+	})
+}
 
-	if pgame.Status != 2 {
-		pplayer.StatusMsgs = append(pplayer.StatusMsgs, fmt.Sprintf("Waiting for %s to play.", popponent.NickName))
-		popponent.StatusMsgs = append(popponent.StatusMsgs, "Your turn to play.")
-		pgame.NextToPlay = popponent.ID
+func (app *application) loginUser(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
 	}
-	popponent.MsgChn <- "refresh"
-	http.Redirect(w, r, fmt.Sprintf("/%s", pgame.ID), http.StatusSeeOther)
+
+	form := forms.New(r.PostForm)
+	form.ValidateLoginForm()
+	if !form.Valid() {
+		app.render(w, r, "login.page.tmpl", &templateData{Form: form})
+		return
+	}
+
+	u, err := app.users.GetByName(form.Get("name"))
+	if err == nil {
+		err = u.Authenticate(form.Get("password"))
+	}
+	if err == models.ErrNoUser || err == models.ErrInvalidCredentials {
+		form.Errors.Add("generic", "Name or password is incorrect")
+		app.render(w, r, "login.page.tmpl", &templateData{Form: form})
+		return
+	}
+	if err != nil {
+		app.serverError(w, err)
+		return
+	}
+
+	app.session.Put(r, "authenticatedUserID", u.ID)
+	http.Redirect(w, r, "/user/profile", http.StatusSeeOther)
+}
+
+func (app *application) logoutUser(w http.ResponseWriter, r *http.Request) {
+	// a game in progress goes on as a guest
+	app.session.Remove(r, "authenticatedUserID")
+	app.session.Put(r, "flash", "You've been logged out successfully.")
+	http.Redirect(w, r, "/start", http.StatusSeeOther)
+}
+
+func (app *application) userProfile(w http.ResponseWriter, r *http.Request) {
+	ptd := &templateData{
+		GameID: app.session.GetString(r, "gameID"),
+	}
+	stats, err := app.stats.Get(app.session.GetString(r, "authenticatedUserID"))
+	switch err {
+	case nil:
+		ptd.Stats = stats
+	case models.ErrNoStats:
+	default:
+		app.serverError(w, err)
+		return
+	}
+	app.render(w, r, "profile.page.tmpl", ptd)
+}
+
+func (app *application) showLeaderboard(w http.ResponseWriter, r *http.Request) {
+	by := r.URL.Query().Get("by")
+	if by != "wins" {
+		by = "rating"
+	}
+	app.render(w, r, "leaderboard.page.tmpl", &templateData{
+		Leaderboard: app.leaderboard(by),
+		SortBy:      by,
+	})
+}
+
+func (app *application) showLobby(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, "lobby.page.tmpl", &templateData{
+		Lobby: app.openGames(),
+		Seq:   app.lobby.Seq(),
+	})
+}
+
+// lobbySse sends the games listed in the lobby and taken out of it
+func (app *application) lobbySse(w http.ResponseWriter, r *http.Request) {
+	app.sendEvents(w, r, app.lobby, app.lobby.Subscribe, func(ev models.Event) (interface{}, error) {
+		return app.newLobbyEvent(ev), nil
+	})
 }
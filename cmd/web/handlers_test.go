@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golangcollege/sessions"
+	"github.com/rjpgt/battleship/pkg/forms"
+	"github.com/rjpgt/battleship/pkg/models"
+	"github.com/rjpgt/battleship/pkg/models/memory"
+)
+
+// newTestApplication returns an application with its stores in memory
+// and its logs discarded
+func newTestApplication(t *testing.T) *application {
+	t.Helper()
+	templateCache, err := newTemplateCache("../../ui/html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := sessions.New([]byte("3dSm5MnygFHh7XidAtbskXrjbwfoJcbJ"))
+	session.Lifetime = 12 * time.Hour
+
+	return &application{
+		cfg: &config{
+			staticDir:    "../../ui/static",
+			maxGames:     100,
+			waitingTTL:   time.Hour,
+			playingTTL:   time.Hour,
+			endedTTL:     time.Hour,
+			abandonGrace: 2 * time.Minute,
+		},
+		errorLog:      log.New(ioutil.Discard, "", 0),
+		games:         memory.New(),
+		infoLog:       log.New(ioutil.Discard, "", 0),
+		lobby:         models.NewBus(),
+		queue:         models.NewQueue(),
+		replays:       memory.NewReplays(),
+		session:       session,
+		stats:         memory.NewStats(),
+		templateCache: templateCache,
+		users:         memory.NewUsers(),
+	}
+}
+
+// apiCall posts body as JSON to the path of the test server, or gets
+// the path if body is nil, with the player's token if there is one,
+// and decodes the reply into reply. It returns the status code.
+func apiCall(t *testing.T, ts *httptest.Server, path, token string, body, reply interface{}) int {
+	method := http.MethodGet
+	var buf bytes.Buffer
+	if body != nil {
+		method = http.MethodPost
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Error(err)
+			return 0
+		}
+	}
+	req, err := http.NewRequest(method, ts.URL+path, &buf)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rs, err := ts.Client().Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer rs.Body.Close()
+	if reply != nil {
+		if err := json.NewDecoder(rs.Body).Decode(reply); err != nil {
+			t.Errorf("%s %s: %v", method, path, err)
+		}
+	}
+	return rs.StatusCode
+}
+
+// playAPIGame plays a game through the API to its end, each player
+// firing at the squares of the board in turn, and returns the
+// nickname of the winner
+func playAPIGame(t *testing.T, ts *httptest.Server, n int) string {
+	var players [2]apiJoined
+	code := apiCall(t, ts, "/api/v1/games", "", apiNewPlayer{
+		Username:  fmt.Sprintf("alice%d", n),
+		AutoPlace: true,
+	}, &players[0])
+	if code != http.StatusCreated {
+		t.Errorf("game %d: create returned %d", n, code)
+		return ""
+	}
+	code = apiCall(t, ts, fmt.Sprintf("/api/v1/games/%s/join", players[0].GameID), "", apiNewPlayer{
+		Username:  fmt.Sprintf("bobby%d", n),
+		AutoPlace: true,
+	}, &players[1])
+	if code != http.StatusOK && code != http.StatusCreated {
+		t.Errorf("game %d: join returned %d", n, code)
+		return ""
+	}
+
+	var next [2]int // next square each player fires at
+	turn := 0       // the creator fires first
+	for shots := 0; shots < 200; shots++ {
+		pos := fmt.Sprintf("%d%d", next[turn]/10, next[turn]%10)
+		next[turn]++
+		var reply apiShotReply
+		path := fmt.Sprintf("/api/v1/games/%s/shots", players[0].GameID)
+		code := apiCall(t, ts, path, players[turn].Token, map[string]string{"square": pos}, &reply)
+		if code != http.StatusOK {
+			t.Errorf("game %d: shot %s returned %d", n, pos, code)
+			return ""
+		}
+		if reply.Game.Status == "ended" {
+			return reply.Game.NickName
+		}
+		if !reply.Game.YourTurn {
+			turn = 1 - turn
+		}
+	}
+	t.Errorf("game %d: not over after 200 shots", n)
+	return ""
+}
+
+func TestParallelAPIGames(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewServer(app.router())
+	defer ts.Close()
+
+	const games = 10
+	var wg sync.WaitGroup
+	for n := 0; n < games; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			winner := playAPIGame(t, ts, n)
+			if winner != fmt.Sprintf("alice%d", n) && winner != fmt.Sprintf("bobby%d", n) {
+				t.Errorf("game %d: won by %q", n, winner)
+			}
+		}(n)
+	}
+	// read the lobby and the leaderboard while the games are played
+	for i := 0; i < games; i++ {
+		rs, err := ts.Client().Get(ts.URL + "/lobby")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Body.Close()
+		if rs.StatusCode != http.StatusOK {
+			t.Errorf("lobby returned %d", rs.StatusCode)
+		}
+		var ranked []apiRanked
+		if code := apiCall(t, ts, "/api/v1/leaderboard", "", nil, &ranked); code != http.StatusOK {
+			t.Errorf("leaderboard returned %d", code)
+		}
+	}
+	wg.Wait()
+
+	ended := 0
+	for _, pgame := range app.games.List() {
+		app.games.View(pgame.ID, func(pgame *models.Game) error {
+			if pgame.Status == models.GameEnded {
+				ended++
+			}
+			return nil
+		})
+		// the replay is kept in the store and can be exported
+		rs, err := ts.Client().Get(ts.URL + "/replay/" + pgame.ID + "/export")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var replay models.Replay
+		err = json.NewDecoder(rs.Body).Decode(&replay)
+		rs.Body.Close()
+		if rs.StatusCode != http.StatusOK || err != nil || replay.ID != pgame.ID {
+			t.Errorf("replay of %s: got %d, %v", pgame.ID, rs.StatusCode, err)
+		}
+	}
+	if ended != games {
+		t.Errorf("%d games ended; want %d", ended, games)
+	}
+}
+
+func TestAPIRejectsRules(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewServer(app.router())
+	defer ts.Close()
+
+	var game apiJoined
+	code := apiCall(t, ts, "/api/v1/games", "", apiNewPlayer{Username: "alice", AutoPlace: true}, &game)
+	if code != http.StatusCreated {
+		t.Fatalf("create returned %d", code)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		req   apiNewPlayer
+		field string
+	}{
+		{"board too small", "/api/v1/games", apiNewPlayer{Username: "bobby", AutoPlace: true, Size: 3}, "size"},
+		{"negative size", "/api/v1/games", apiNewPlayer{Username: "bobby", AutoPlace: true, Size: -1}, "size"},
+		{"size not offered", "/api/v1/games", apiNewPlayer{Username: "bobby", AutoPlace: true, Size: 9}, "size"},
+		{"unknown ruleset", "/api/v1/games", apiNewPlayer{Username: "bobby", AutoPlace: true, Ruleset: "armada"}, "ruleset"},
+		{"join with a bad size", "/api/v1/games/" + game.GameID + "/join", apiNewPlayer{Username: "bobby", AutoPlace: true, Size: -1}, "size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reply apiErrorReply
+			code := apiCall(t, ts, tt.path, "", tt.req, &reply)
+			if code != http.StatusUnprocessableEntity {
+				t.Errorf("got %d; want %d", code, http.StatusUnprocessableEntity)
+			}
+			if len(reply.Fields[tt.field]) == 0 {
+				t.Errorf("got %+v; want an error on %s", reply, tt.field)
+			}
+		})
+	}
+}
+
+func TestPlayGameFormNotFound(t *testing.T) {
+	app := newTestApplication(t)
+	rules := models.Rulesets[0]
+	fleet, err := models.RandomFleet(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleet.Set("username", "alice")
+	pgame, err := models.NewGame(fleet, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.games.Put(pgame); err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler is reached without the checks of belongsToGame,
+	// as when the game changes in between
+	handler := app.session.Enable(http.HandlerFunc(app.playGameForm))
+	for _, gameID := range []string{"nosuchgame", pgame.ID} {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/"+gameID+"?:gameid="+gameID, nil)
+		handler.ServeHTTP(rr, r)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("game %s: got %d; want %d", gameID, rr.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestWatchGameNotFound(t *testing.T) {
+	app := newTestApplication(t)
+
+	// the handler is reached without the check of gameExists, as when
+	// the game is removed in between
+	handler := app.session.Enable(http.HandlerFunc(app.watchGame))
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/watch/nosuchgame?:gameid=nosuchgame", nil)
+	handler.ServeHTTP(rr, r)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("got %d; want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestJoinAfterLeave(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewServer(app.router())
+	defer ts.Close()
+
+	var alice, bobby apiJoined
+	if code := apiCall(t, ts, "/api/v1/games", "", apiNewPlayer{Username: "alice", AutoPlace: true}, &alice); code != http.StatusCreated {
+		t.Fatalf("create returned %d", code)
+	}
+	path := "/api/v1/games/" + alice.GameID
+	if code := apiCall(t, ts, path+"/join", "", apiNewPlayer{Username: "bobby", AutoPlace: true}, &bobby); code != http.StatusCreated {
+		t.Fatalf("join returned %d", code)
+	}
+	var state apiGame
+	if code := apiCall(t, ts, path+"/resign", bobby.Token, struct{}{}, &state); code != http.StatusOK {
+		t.Fatalf("resign returned %d", code)
+	}
+	if err := app.leave(alice.GameID, bobby.PlayerID); err != nil {
+		t.Fatal(err)
+	}
+
+	var reply apiErrorReply
+	code := apiCall(t, ts, path+"/join", "", apiNewPlayer{Username: "carla", AutoPlace: true}, &reply)
+	if code != http.StatusConflict {
+		t.Errorf("API join after leave: got %d; want %d", code, http.StatusConflict)
+	}
+
+	// the join page sends the player back to the start page
+	client := ts.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	rs, err := client.Get(ts.URL + "/join/" + alice.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs.Body.Close()
+	if rs.StatusCode != http.StatusSeeOther || rs.Header.Get("Location") != "/start" {
+		t.Errorf("join page after leave: got %d to %q; want a redirect to /start", rs.StatusCode, rs.Header.Get("Location"))
+	}
+
+	// nor does the game start again behind the check of the handlers
+	_, err = app.addPlayer(alice.GameID, forms.New(mustFleet(t, "carla")), models.Rulesets[0], "")
+	if err != errGameFull {
+		t.Errorf("addPlayer after leave: got %v; want errGameFull", err)
+	}
+	app.games.View(alice.GameID, func(pgame *models.Game) error {
+		if pgame.Status != models.GameEnded || len(pgame.Players) != 1 {
+			t.Errorf("got status %d with %d players; want the ended game with alice", pgame.Status, len(pgame.Players))
+		}
+		return nil
+	})
+}
+
+// mustFleet returns a new game form with a random standard fleet
+func mustFleet(t *testing.T, username string) url.Values {
+	t.Helper()
+	fleet, err := models.RandomFleet(models.Rulesets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleet.Set("username", username)
+	return fleet
+}
+
+func TestJoinOwnGame(t *testing.T) {
+	app := newTestApplication(t)
+	pgame, _, err := app.newGame(forms.New(mustFleet(t, "alice")), models.Rulesets[0], "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = app.addPlayer(pgame.ID, forms.New(mustFleet(t, "alice2")), models.Rulesets[0], "u1")
+	if err != errOwnGame {
+		t.Errorf("joined from the same account: got %v; want errOwnGame", err)
+	}
+	if _, err = app.addPlayer(pgame.ID, forms.New(mustFleet(t, "bobby")), models.Rulesets[0], "u2"); err != nil {
+		t.Errorf("joined from another account: %v", err)
+	}
+}
+
+func TestLobbyKeepsPrivateGames(t *testing.T) {
+	app := newTestApplication(t)
+	_, events, _ := app.lobby.Subscribe(0)
+	defer app.lobby.Unsubscribe(events)
+
+	// lobbyIDs returns the IDs of the games in the lobby events so far
+	lobbyIDs := func() []string {
+		ids := []string{}
+		for {
+			select {
+			case ev := <-events:
+				ids = append(ids, ev.GameID)
+			default:
+				return ids
+			}
+		}
+	}
+
+	form := forms.New(mustFleet(t, "alice"))
+	form.Set("private", "on")
+	private, _, err := app.newGame(form, models.Rulesets[0], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.addPlayer(private.ID, forms.New(mustFleet(t, "bobby")), models.Rulesets[0], ""); err != nil {
+		t.Fatal(err)
+	}
+	if ids := lobbyIDs(); len(ids) != 0 {
+		t.Errorf("a private game was sent to the lobby: %v", ids)
+	}
+
+	public, _, err := app.newGame(forms.New(mustFleet(t, "carla")), models.Rulesets[0], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.addPlayer(public.ID, forms.New(mustFleet(t, "derek")), models.Rulesets[0], ""); err != nil {
+		t.Fatal(err)
+	}
+	// opened, then closed by the join
+	if ids := lobbyIDs(); len(ids) != 2 || ids[0] != public.ID || ids[1] != public.ID {
+		t.Errorf("got lobby events for %v; want 2 for %s", ids, public.ID)
+	}
+
+	app.reap(time.Now().Add(2 * app.cfg.playingTTL))
+	if ids := lobbyIDs(); len(ids) != 0 {
+		t.Errorf("the removal of games in progress was sent to the lobby: %v", ids)
+	}
+}
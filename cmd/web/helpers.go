@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime/debug"
-	"time"
+	"sort"
+	"strconv"
+
+	"github.com/rjpgt/battleship/pkg/forms"
+	"github.com/rjpgt/battleship/pkg/models"
 )
 
 // The serverError helper writes an error message and stack trace to the errorLog,
@@ -35,8 +40,46 @@ func (app *application) addDefaultData(td *templateData, r *http.Request) *templ
 		td = &templateData{}
 	}
 	td.Flash = app.session.PopString(r, "flash")
+	td.User = app.authenticatedUser(r)
 	return td
 }
+
+// authenticatedUser returns the account the request is logged in
+// to, or nil for a guest
+func (app *application) authenticatedUser(r *http.Request) *models.User {
+	id := app.session.GetString(r, "authenticatedUserID")
+	if id == "" {
+		return nil
+	}
+	u, err := app.users.Get(id)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// playAs makes the nickname of a logged in user the account name
+// on a posted new game or join form, and returns the account's ID,
+// which is empty for a guest
+func (app *application) playAs(r *http.Request) string {
+	u := app.authenticatedUser(r)
+	if u == nil {
+		return ""
+	}
+	r.PostForm.Set("username", u.Name)
+	return u.ID
+}
+
+// reserveNames keeps guests from playing under the name of an account
+func (app *application) reserveNames(form *forms.Form, userID string) {
+	if userID != "" {
+		return
+	}
+	if _, err := app.users.GetByName(form.Get("username")); err == nil {
+		form.Errors.Add("username", "This name belongs to an account. Log in to play as it.")
+	}
+}
+
 func (app *application) render(w http.ResponseWriter, r *http.Request, name string, td *templateData) {
 	ts, ok := app.templateCache[name]
 	if !ok {
@@ -55,8 +98,96 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, name stri
 	buf.WriteTo(w)
 }
 
-func (app *application) gameTimeout(gameID string) {
-	time.Sleep(GameTimeout * time.Hour)
-	delete(app.gameModel.Games, gameID)
-	app.infoLog.Printf("Removing game %s after timeout.", gameID)
+// autoPlace fills the ship fields of a posted new game form with a
+// random fleet of the ruleset if the player pressed the auto-place
+// button, and reports whether it did.
+func autoPlace(r *http.Request, rules models.Ruleset) (bool, error) {
+	if r.PostForm.Get("autoplace") == "" {
+		return false, nil
+	}
+	fleet, err := models.RandomFleet(rules)
+	if err != nil {
+		return false, err
+	}
+	r.PostForm.Del("autoplace")
+	for field, posns := range fleet {
+		r.PostForm[field] = posns
+	}
+	return true, nil
+}
+
+// boardSize reads the board size chosen on the new game form,
+// falling back to the given size
+func boardSize(form url.Values, fallback int) int {
+	size, err := strconv.Atoi(form.Get("size"))
+	if err != nil || !models.ValidSize(size) {
+		return fallback
+	}
+	return size
+}
+
+// seriesLength reads the length of the series asked for with a
+// rematch, falling back to an open-ended series
+func seriesLength(form url.Values) int {
+	bestOf, err := strconv.Atoi(form.Get("bestof"))
+	if err != nil {
+		return 0
+	}
+	for _, length := range models.SeriesLengths {
+		if bestOf == length {
+			return bestOf
+		}
+	}
+	return 0
+}
+
+// activeGames counts the games of the store that have not ended.
+// Ended games wait for a rematch and do not count against max-games.
+func (app *application) activeGames() int {
+	n := 0
+	for _, pgame := range app.games.List() {
+		app.games.View(pgame.ID, func(pgame *models.Game) error {
+			if pgame.Status != models.GameEnded {
+				n++
+			}
+			return nil
+		})
+	}
+	return n
+}
+
+// leaderboard ranks the players with recorded games by rating or,
+// if by is "wins", by wins. Ties go to the better rating, then
+// the better record, then by name.
+func (app *application) leaderboard(by string) []*models.Stats {
+	stats := app.stats.List()
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if by == "wins" && a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		if a.Wins-a.Losses != b.Wins-b.Losses {
+			return a.Wins-a.Losses > b.Wins-b.Losses
+		}
+		return a.Name < b.Name
+	})
+	return stats
+}
+
+// lastEventID gives the number of the last event a page got: the
+// Last-Event-ID header sent by EventSource when it reconnects, or
+// else the after parameter set by the page from its data-seq.
+func lastEventID(r *http.Request) int {
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		id = r.URL.Query().Get("after")
+	}
+	seq, err := strconv.Atoi(id)
+	if err != nil {
+		return 0
+	}
+	return seq
 }
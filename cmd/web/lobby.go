@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// lobbyGame is a game listed in the lobby, waiting for a second player
+type lobbyGame struct {
+	ID      string    `json:"id"`
+	Creator string    `json:"creator"`
+	Ruleset string    `json:"ruleset"`
+	Size    int       `json:"size"`
+	Ranked  bool      `json:"ranked"`
+	Created time.Time `json:"created"`
+}
+
+// lobbyEvent is what the lobby page gets when a game is listed or
+// taken out of the list. Game is nil for a game taken out.
+type lobbyEvent struct {
+	Seq    int        `json:"seq"`
+	Type   string     `json:"type"` // "opened" or "closed"
+	GameID string     `json:"game_id"`
+	Game   *lobbyGame `json:"game,omitempty"`
+}
+
+// newLobbyGame gives what the lobby shows of a listed game.
+// The caller must hold the game's Mu.
+func newLobbyGame(pgame *models.Game) *lobbyGame {
+	lg := &lobbyGame{
+		ID:      pgame.ID,
+		Ruleset: pgame.Rules.Label,
+		Size:    pgame.Rules.Size,
+		Ranked:  pgame.Ranked,
+		Created: pgame.Created,
+	}
+	if pcreator, ok := pgame.Players[pgame.Owner]; ok {
+		lg.Creator = pcreator.NickName
+	}
+	return lg
+}
+
+// openGames lists the games of the lobby, oldest first
+func (app *application) openGames() []*lobbyGame {
+	games := []*lobbyGame{}
+	for _, pgame := range app.games.List() {
+		app.games.View(pgame.ID, func(pgame *models.Game) error {
+			if pgame.Listed() {
+				games = append(games, newLobbyGame(pgame))
+			}
+			return nil
+		})
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].Created.Before(games[j].Created)
+	})
+	return games
+}
+
+// lobbyChanged tells the lobby pages that the game may have been
+// listed or taken out of the list. The pages look the game up when
+// they send the event, so it is published after the change is made.
+// It is called only for a game that was listed before the change or
+// is after it, so that the IDs of private games are not sent.
+func (app *application) lobbyChanged(gameID string) {
+	app.lobby.Publish(models.Event{Type: models.EventLobby, GameID: gameID})
+}
+
+// newLobbyEvent gives what the lobby pages get for an EventLobby
+// event, from the game as it is now
+func (app *application) newLobbyEvent(ev models.Event) lobbyEvent {
+	lev := lobbyEvent{Seq: ev.Seq, Type: "closed", GameID: ev.GameID}
+	app.games.View(ev.GameID, func(pgame *models.Game) error {
+		if pgame.Listed() {
+			lev.Type = "opened"
+			lev.Game = newLobbyGame(pgame)
+		}
+		return nil
+	})
+	return lev
+}
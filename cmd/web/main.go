@@ -3,69 +3,139 @@ package main
 import (
 	"html/template"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/golangcollege/sessions"
 	"github.com/rjpgt/battleship/pkg/models"
+	"github.com/rjpgt/battleship/pkg/models/jsonfile"
 )
 
 type application struct {
+	cfg           *config
 	errorLog      *log.Logger
-	gameModel     *models.GameModel
+	games         models.GameStore
 	infoLog       *log.Logger
+	lobby         *models.Bus // events of the games listed in the lobby
+	queue         *models.Queue
+	replays       models.ReplayStore
 	session       *sessions.Session
+	stats         models.StatsStore
 	templateCache map[string]*template.Template
+	users         models.UserStore
 }
 
-const GameTimeout = 5
-const MaxGames = 5
+// ReapInterval is how often the games are swept for idle ones
+const ReapInterval = time.Minute
+
+// MatchGrace is how long a player waiting for an opponent
+// stays in the matchmaking queue with the waiting page closed
+const MatchGrace = 10 * time.Second
+
+// AwayNotice is how long a player of a game in progress may have
+// no page of the game open, as while a page reloads, before the
+// opponent is told the player has gone
+const AwayNotice = 5 * time.Second
 
 func main() {
-	infoFile, err := os.OpenFile("info.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	infoFile, err := os.OpenFile(cfg.infoLog, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer infoFile.Close()
 	infoLog := log.New(infoFile, "INFO\t", log.Ldate|log.Ltime)
 
-	errFile, err := os.OpenFile("err.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
+	errFile, err := os.OpenFile(cfg.errorLog, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer errFile.Close()
 	errorLog := log.New(errFile, "ERROR\t", log.Ldate|log.Ltime|log.Lshortfile)
 
-	templateCache, err := newTemplateCache("./ui/html")
+	rand.Seed(time.Now().UnixNano())
+
+	if cfg.randomSecret {
+		infoLog.Print("No secret is set, and sessions will not outlive the server")
+	}
+
+	templateCache, err := newTemplateCache(cfg.htmlDir)
 	if err != nil {
 		errorLog.Fatal(err)
 	}
 
-	session := sessions.New([]byte("2NJJssnekSBl3n0k@cg;S<B2rtleLPyw"))
+	session := sessions.New([]byte(cfg.secret))
 	session.Lifetime = 12 * time.Hour
 	session.HttpOnly = false
 	session.Persist = false
 
-	// This is our DB
-	games := map[string]*models.Game{}
+	games, err := jsonfile.Open(cfg.dataDir)
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	replays, err := jsonfile.OpenReplays(filepath.Join(cfg.dataDir, "replays"))
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	users, err := jsonfile.OpenUsers(filepath.Join(cfg.dataDir, "users", "users.json"))
+	if err != nil {
+		errorLog.Fatal(err)
+	}
+
+	stats, err := jsonfile.OpenStats(filepath.Join(cfg.dataDir, "stats", "stats.json"))
+	if err != nil {
+		errorLog.Fatal(err)
+	}
 
 	app := &application{
+		cfg:           cfg,
 		errorLog:      errorLog,
-		gameModel:     &models.GameModel{Games: games},
+		games:         games,
 		infoLog:       infoLog,
+		lobby:         models.NewBus(),
+		queue:         models.NewQueue(),
+		replays:       replays,
 		session:       session,
+		stats:         stats,
 		templateCache: templateCache,
+		users:         users,
 	}
 
-	addr := ":8000"
+	// restart the clocks of the games saved before a restart, and
+	// record the results that were not recorded before it. Games saved
+	// before their activity was kept count as active from the restart.
+	for _, pgame := range games.List() {
+		games.Update(pgame.ID, func(pgame *models.Game) error {
+			if pgame.Active.IsZero() {
+				pgame.Active = time.Now()
+			}
+			app.armClock(pgame)
+			return nil
+		})
+		err = app.recordResult(pgame.ID)
+		if err != nil {
+			errorLog.Print(err)
+		}
+	}
+
+	go app.reapGames()
+
 	srv := &http.Server{
-		Addr:     addr,
+		Addr:     cfg.addr,
 		ErrorLog: errorLog,
 		Handler:  app.router(),
 	}
 
-	infoLog.Printf("Starting server on %s", addr)
+	infoLog.Printf("Starting server on %s", cfg.addr)
 	err = srv.ListenAndServe()
 	errorLog.Fatal(err)
 }
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+
+	"github.com/rjpgt/battleship/pkg/models"
 )
 
+type contextKey string
+
+// contextKeyWriter holds the ResponseWriter given by the server, set by keepWriter
+const contextKeyWriter = contextKey("writer")
+
 func secureHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
@@ -38,7 +46,7 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 func (app *application) gameExists(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gameID := r.URL.Query().Get(":gameid")
-		_, ok := app.gameModel.Games[gameID]
+		_, ok := app.games.Get(gameID)
 		if !ok {
 			app.session.Put(r, "flash", "No such game or game has expired. Create a new game.")
 			http.Redirect(w, r, "/start", http.StatusSeeOther)
@@ -51,8 +59,12 @@ func (app *application) gameExists(next http.Handler) http.Handler {
 
 func (app *application) canJoin(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		pgame, _ := app.gameModel.Games[r.URL.Query().Get(":gameid")]
-		if len(pgame.Players) == 2 {
+		full := true
+		app.games.View(r.URL.Query().Get(":gameid"), func(pgame *models.Game) error {
+			full = !pgame.Joinable()
+			return nil
+		})
+		if full {
 			app.session.Put(r, "flash", "Game is full. Start another.")
 			http.Redirect(w, r, "/start", http.StatusSeeOther)
 			return
@@ -62,6 +74,23 @@ func (app *application) canJoin(next http.Handler) http.Handler {
 	})
 }
 
+func (app *application) canWatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := false
+		app.games.View(r.URL.Query().Get(":gameid"), func(pgame *models.Game) error {
+			allowed = pgame.Spectators
+			return nil
+		})
+		if !allowed {
+			app.session.Put(r, "flash", "Spectators are not allowed in this game.")
+			http.Redirect(w, r, "/start", http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) belongsToGame(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		gameID := r.URL.Query().Get(":gameid")
@@ -71,9 +100,12 @@ func (app *application) belongsToGame(next http.Handler) http.Handler {
 			return
 		}
 
-		pgame, _ := app.gameModel.Games[gameID]
 		playerID := app.session.GetString(r, "playerID")
-		_, ok := pgame.Players[playerID]
+		ok := false
+		app.games.View(gameID, func(pgame *models.Game) error {
+			_, ok = pgame.Players[playerID]
+			return nil
+		})
 		if !ok {
 			app.session.Put(r, "flash", "You are not a part of this game. Create a new game.")
 			http.Redirect(w, r, "/start", http.StatusSeeOther)
@@ -83,3 +115,26 @@ func (app *application) belongsToGame(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requireAuthentication sends guests to the login page
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.authenticatedUser(r) == nil {
+			app.session.Put(r, "flash", "Please log in first.")
+			http.Redirect(w, r, "/user/login", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// keepWriter puts the ResponseWriter given by the server in the request
+// context. session.Enable hands the handlers after it a writer that
+// holds everything until the handler returns, so a handler that streams
+// its response, like handleSse, writes to this one instead.
+func keepWriter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKeyWriter, w)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
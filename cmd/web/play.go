@@ -0,0 +1,699 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/ai"
+	"github.com/rjpgt/battleship/pkg/forms"
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// The game actions below are shared by the HTML handlers and the API.
+
+// errGameFull is returned by addPlayer when another player has
+// joined the game first, or the game no longer waits for a player
+var errGameFull = errors.New("game is full")
+
+// errOwnGame is returned by addPlayer when the player's account is the
+// one of the first player, as the same account may not win and lose a
+// game. Matchmaking never pairs an account with itself either.
+var errOwnGame = errors.New("game was started by the same account")
+
+// errInvalidShot is returned by fire when the fire form
+// does not hold a square of the board
+var errInvalidShot = errors.New("invalid firing position")
+
+// validateNewGame validates a form that starts a game played by the ruleset
+func validateNewGame(form *forms.Form, rules models.Ruleset) {
+	form.ValidateNewGameForm(rules)
+	levels := []string{}
+	for _, level := range ai.Levels {
+		levels = append(levels, level.Name)
+	}
+	form.PermittedValues("level", levels...)
+	clocks := []string{}
+	for _, clock := range models.TimeControls {
+		clocks = append(clocks, clock.Name)
+	}
+	form.PermittedValues("clock", clocks...)
+	form.PermittedValues("timeout", "forfeit", "autofire")
+}
+
+// validateRanked checks that a new game form asking for a ranked game
+// comes from a player with an account, userID, who does not play the
+// computer
+func validateRanked(form *forms.Form, userID string) {
+	if form.Get("ranked") == "" {
+		return
+	}
+	if userID == "" {
+		form.Errors.Add("ranked", "Log in to play ranked games")
+	}
+	if form.Get("vs_computer") != "" {
+		form.Errors.Add("ranked", "Ranked games are played against other players")
+	}
+}
+
+// validateMatch checks a new game form whose player asks for an
+// opponent from the matchmaking queue. userID is the player's account.
+func validateMatch(form *forms.Form, userID string) {
+	if form.Get("match") == "" {
+		return
+	}
+	if form.Get("vs_computer") != "" {
+		form.Errors.Add("match", "Matchmaking finds human opponents")
+	}
+	if form.Get("close_rating") != "" && userID == "" {
+		form.Errors.Add("match", "Log in to be paired by rating")
+	}
+}
+
+// newGame makes a game from a valid new game form, adds the computer
+// opponent if the form asks for one and puts the game in the store.
+// userID is the creator's account, empty for a guest. It returns the
+// game and the ID of its creator.
+func (app *application) newGame(form *forms.Form, rules models.Ruleset, userID string) (*models.Game, string, error) {
+	pgame, err := models.NewGame(form.Values, rules)
+	if err != nil {
+		return nil, "", err
+	}
+	// only the creator in Players at this stage; read it before
+	// the game is shared through the store
+	var playerID string
+	for id, pplayer := range pgame.Players {
+		playerID = id
+		pplayer.UserID = userID
+	}
+	pgame.Ranked = form.Get("ranked") != ""
+	pgame.Private = form.Get("private") != ""
+	// set before a computer opponent joins and starts the clocks
+	pgame.Clock = models.LookupTimeControl(form.Get("clock"))
+	pgame.Clock.AutoFire = form.Get("timeout") == "autofire"
+	if form.Get("vs_computer") != "" {
+		pbot, err := pgame.AddBot(form.Get("level"))
+		if err != nil {
+			return nil, "", err
+		}
+		pgame.Players[playerID].StatusMsgs = []string{
+			fmt.Sprintf("You are playing against the %s.", pbot.NickName),
+			"It's your turn to play.",
+		}
+		app.armClock(pgame)
+	}
+	listed := pgame.Listed()
+	err = app.games.Put(pgame)
+	if err != nil {
+		return nil, "", err
+	}
+	if listed {
+		app.lobbyChanged(pgame.ID)
+	}
+	return pgame, playerID, nil
+}
+
+// findOpponent pairs the player of a valid new game form with the
+// player who has waited longest in the matchmaking queue among those
+// it may play, or else puts the player in the queue. userID is the
+// player's account, empty for a guest. It returns the ID of the
+// player's seeker if the player waits, or else the game made for the
+// pair and the ID of the player in it.
+func (app *application) findOpponent(form *forms.Form, rules models.Ruleset, userID string) (seekerID, gameID, playerID string, err error) {
+	rating := models.InitialRating
+	if userID != "" {
+		if pstats, err := app.stats.Get(userID); err == nil {
+			rating = pstats.Rating
+		}
+	}
+	pseeker, err := models.NewSeeker(form.Values, rules, userID, rating)
+	if err != nil {
+		return "", "", "", err
+	}
+	pseeker.Ranked = form.Get("ranked") != ""
+	pseeker.AnyRules = form.Get("any_rules") != ""
+	pseeker.CloseRating = form.Get("close_rating") != ""
+
+	pwaiting := app.queue.Add(pseeker)
+	if pwaiting == nil {
+		return pseeker.ID, "", "", nil
+	}
+	gameID, playerID, err = app.pair(pwaiting, pseeker)
+	if err != nil {
+		// the waiting page tells its player to try again
+		app.paired(pwaiting.ID, "", "")
+		return "", "", "", err
+	}
+	return "", gameID, playerID, nil
+}
+
+// pair makes the game of two seekers paired by the matchmaking queue,
+// as if the one who waited had started it and the other had joined it.
+// The game is played by the rules of the one who waited, and the
+// fleet of the other is placed at random if it chose other rules. It
+// returns the game and the ID of the player who joined, and tells the
+// waiting page of the other.
+func (app *application) pair(pwaiting, pjoining *models.Seeker) (string, string, error) {
+	form := forms.New(pwaiting.Form)
+	// not listed in the lobby, where another player could join
+	// the game before pjoining does
+	form.Set("private", "on")
+	pgame, playerID, err := app.newGame(form, pwaiting.Rules, pwaiting.UserID)
+	if err != nil {
+		return "", "", err
+	}
+
+	values := pjoining.Form
+	if !pjoining.SameRules(pwaiting) {
+		fleet, err := models.RandomFleet(pwaiting.Rules)
+		if err != nil {
+			return "", "", err
+		}
+		for field, posns := range fleet {
+			values[field] = posns
+		}
+	}
+	pplayer2, err := app.addPlayer(pgame.ID, forms.New(values), pwaiting.Rules, pjoining.UserID)
+	if err != nil {
+		return "", "", err
+	}
+	app.paired(pwaiting.ID, pgame.ID, playerID)
+	return pgame.ID, pplayer2.ID, nil
+}
+
+// paired records the game made for a seeker that waited, and takes
+// the seeker out if no waiting page of it is open by MatchGrace
+func (app *application) paired(seekerID, gameID, playerID string) {
+	app.queue.Paired(seekerID, gameID, playerID)
+	time.AfterFunc(MatchGrace, func() {
+		app.queue.CancelIdle(seekerID)
+	})
+}
+
+// addPlayer makes the player of a valid join form the second player
+// of the game and tells the first player. userID is the player's
+// account, empty for a guest. It returns errGameFull if the game
+// does not wait for a second player, and errOwnGame if userID started
+// it.
+func (app *application) addPlayer(gameID string, form *forms.Form, rules models.Ruleset, userID string) (*models.Player, error) {
+	pplayer2, err := models.NewPlayer(form.Values, rules)
+	if err != nil {
+		return nil, err
+	}
+	pplayer2.UserID = userID
+
+	var refused error
+	listed := false
+	err = app.games.Update(gameID, func(pgame *models.Game) error {
+		// another player may have joined since the caller looked
+		if !pgame.Joinable() {
+			refused = errGameFull
+			return nil
+		}
+		var pplayer1 *models.Player
+		// only 1 player in Players at this stage
+		for _, pplayer := range pgame.Players {
+			pplayer1 = pplayer
+		}
+		if userID != "" && pplayer1.UserID == userID {
+			refused = errOwnGame
+			return nil
+		}
+		listed = pgame.Listed()
+
+		pgame.Join(pplayer2)
+		app.armClock(pgame)
+		pplayer2.StatusMsgs = []string{
+			fmt.Sprintf("Waiting for %s to play.", pplayer1.NickName),
+		}
+		pplayer1.StatusMsgs = []string{
+			fmt.Sprintf("%s has joined the game", pplayer2.NickName),
+			"It's your turn to play.",
+		}
+		pgame.Events.Publish(models.Event{
+			Type:     models.EventJoined,
+			PlayerID: pplayer2.ID,
+			NickName: pplayer2.NickName,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refused != nil {
+		return nil, refused
+	}
+	if listed {
+		app.lobbyChanged(gameID)
+	}
+	return pplayer2, nil
+}
+
+// fire fires playerID's shot at the square of the fire form. A
+// computer opponent then fires until the turn passes back. The status
+// messages of both players are updated, including for shots that are
+// refused. It returns the result of playerID's shot, or errInvalidShot,
+// models.ErrRepeatedShot, models.ErrNotYourTurn or
+// models.ErrGameNotActive for a shot that is refused.
+func (app *application) fire(gameID, playerID string, form *forms.Form) (models.ShotResult, error) {
+	var result models.ShotResult
+	var refused error
+	err := app.games.Update(gameID, func(pgame *models.Game) error {
+		if pgame.NextToPlay != playerID {
+			refused = models.ErrNotYourTurn
+			return nil
+		}
+
+		pplayer := pgame.Players[playerID]
+		form.ValidateFireForm(pgame.Rules.Size)
+		if !form.Valid() {
+			pplayer.StatusMsgs = append(pplayer.StatusMsgs, "You have entered an invalid firing position. Try again.")
+			refused = errInvalidShot
+			return nil
+		}
+
+		pos, _ := models.ParseCoord(form.Get("target_pos"))
+		var err error
+		result, err = pgame.Fire(playerID, pos)
+		switch err {
+		case nil:
+		case models.ErrNotYourTurn, models.ErrGameNotActive:
+			refused = err
+			return nil
+		case models.ErrRepeatedShot:
+			pplayer.StatusMsgs = append(pplayer.StatusMsgs, fmt.Sprintf("You have already fired at %s. Try again.", pos))
+			refused = err
+			return nil
+		default:
+			return err
+		}
+
+		pplayer.StatusMsgs = pplayer.StatusMsgs[:0]
+		popponent := pgame.Players[pplayer.OpponentID]
+		popponent.StatusMsgs = popponent.StatusMsgs[:0]
+		reportShot(pplayer, popponent, result, pgame.NextToPlay == pplayer.ID)
+		fired := []firedShot{{pplayer, pplayer.Shots[len(pplayer.Shots)-1]}}
+		replies, err := botTurn(pgame, popponent)
+		if err != nil {
+			return err
+		}
+		publishShots(pgame, append(fired, replies...))
+		app.armClock(pgame)
+		if pgame.Status == models.GameEnded {
+			app.gameOver(pgame)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, refused
+}
+
+// rematch records that playerID wants a rematch of the ended game and
+// tells the opponent. Once both players want one, the rematch is put
+// in the store and its ID returned; until then the ID is empty. It
+// returns models.ErrGameNotOver for a game still in progress and
+// models.ErrNoSuchPlayer if the opponent has left.
+func (app *application) rematch(gameID, playerID string, req models.RematchRequest) (string, error) {
+	var pnext *models.Game
+	nextID := ""
+	err := app.games.Update(gameID, func(pgame *models.Game) error {
+		if pgame.NextID != "" {
+			nextID = pgame.NextID
+			return nil
+		}
+		var err error
+		pnext, err = pgame.AskRematch(playerID, req)
+		if err != nil {
+			return err
+		}
+
+		pplayer := pgame.Players[playerID]
+		popponent := pgame.Players[pplayer.OpponentID]
+		if pnext == nil {
+			pplayer.StatusMsgs = append(pplayer.StatusMsgs, fmt.Sprintf("Waiting for %s to accept the rematch.", popponent.NickName))
+			popponent.StatusMsgs = append(popponent.StatusMsgs, fmt.Sprintf("%s wants a rematch.", pplayer.NickName))
+		} else {
+			// a computer that fires first does so before anyone
+			// sees the rematch
+			for _, pbot := range pnext.Players {
+				fired, err := botTurn(pnext, pbot)
+				if err != nil {
+					pgame.NextID = ""
+					return err
+				}
+				publishShots(pnext, fired)
+			}
+			app.armClock(pnext)
+			// the pages reload on the event below and must find the rematch
+			err = app.games.Put(pnext)
+			if err != nil {
+				pgame.NextID = ""
+				return err
+			}
+			nextID = pnext.ID
+		}
+		pgame.Events.Publish(models.Event{
+			Type:     models.EventRematch,
+			PlayerID: playerID,
+			NickName: pplayer.NickName,
+		})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return nextID, nil
+}
+
+// leave takes playerID out of the ended game and tells the opponent,
+// who can then no longer get a rematch. The game is deleted once no
+// human player is left in it. It returns models.ErrGameNotOver for a
+// game still in progress.
+func (app *application) leave(gameID, playerID string) error {
+	// the result needs both players
+	err := app.recordResult(gameID)
+	if err != nil {
+		return err
+	}
+	empty := true
+	err = app.games.Update(gameID, func(pgame *models.Game) error {
+		if pgame.Status != models.GameEnded {
+			return models.ErrGameNotOver
+		}
+		pplayer, ok := pgame.Players[playerID]
+		if !ok {
+			return models.ErrNoSuchPlayer
+		}
+		delete(pgame.Players, playerID)
+		if popponent, ok := pgame.Players[pplayer.OpponentID]; ok {
+			popponent.StatusMsgs = append(popponent.StatusMsgs, fmt.Sprintf("%s has left.", pplayer.NickName))
+		}
+		for _, pplayer := range pgame.Players {
+			if !pplayer.Bot {
+				empty = false
+			}
+		}
+		pgame.Events.Publish(models.Event{
+			Type:     models.EventLeft,
+			PlayerID: playerID,
+			NickName: pplayer.NickName,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if empty {
+		return app.games.Delete(gameID)
+	}
+	return nil
+}
+
+// resign ends the game in progress as lost by playerID, who gives up.
+// It returns models.ErrGameNotActive if the game is not in progress.
+func (app *application) resign(gameID, playerID string) error {
+	return app.games.Update(gameID, func(pgame *models.Game) error {
+		return app.forfeit(pgame, playerID, forfeitResigned)
+	})
+}
+
+// The reasons a player forfeits a game, as told to the pages
+const (
+	forfeitTime      = "time"      // the player ran out of time
+	forfeitResigned  = "resigned"  // the player resigned
+	forfeitAbandoned = "abandoned" // the player closed the page and did not come back
+)
+
+// forfeit ends the game in progress as lost by playerID for the reason
+// given, tells both players and keeps the replay. The caller must hold
+// the game's Mu, and records the result once the game is saved.
+func (app *application) forfeit(pgame *models.Game, playerID, reason string) error {
+	err := pgame.Forfeit(playerID)
+	if err != nil {
+		return err
+	}
+	pplayer := pgame.Players[playerID]
+	popponent := pgame.Players[pplayer.OpponentID]
+	switch reason {
+	case forfeitTime:
+		pplayer.StatusMsgs = []string{"You ran out of time and lost the game."}
+		popponent.StatusMsgs = []string{fmt.Sprintf("%s ran out of time. You have won the game!", pplayer.NickName)}
+	case forfeitResigned:
+		pplayer.StatusMsgs = []string{"You resigned and lost the game."}
+		popponent.StatusMsgs = []string{fmt.Sprintf("%s resigned. You have won the game!", pplayer.NickName)}
+	case forfeitAbandoned:
+		pplayer.StatusMsgs = []string{"You were away too long and lost the game."}
+		popponent.StatusMsgs = []string{fmt.Sprintf("%s did not come back. You have won the game!", pplayer.NickName)}
+	}
+	pgame.Events.Publish(models.Event{
+		Type:     models.EventForfeit,
+		PlayerID: playerID,
+		NickName: pplayer.NickName,
+		Text:     reason,
+	})
+	app.armClock(pgame)
+	app.gameOver(pgame)
+	return nil
+}
+
+// chat sends playerID's message to the opponent. It returns the
+// errors of models.Game.Say for a message that is not sent.
+func (app *application) chat(gameID, playerID, text string) error {
+	return app.games.Update(gameID, func(pgame *models.Game) error {
+		msg, err := pgame.Say(playerID, text)
+		if err != nil {
+			return err
+		}
+		pgame.Events.Publish(models.Event{
+			Type:     models.EventChat,
+			PlayerID: playerID,
+			NickName: msg.NickName,
+			Text:     msg.Text,
+			Time:     msg.Time,
+		})
+		return nil
+	})
+}
+
+// chatError tells a player why a chat message was not sent, or returns
+// an empty string if err is not about the message
+func chatError(err error) string {
+	switch err {
+	case models.ErrChatEmpty:
+		return "Type a message to send."
+	case models.ErrChatTooLong:
+		return fmt.Sprintf("Messages are at most %d characters long.", models.MaxChatLength)
+	case models.ErrChatMuted:
+		return "The chat is muted."
+	}
+	return ""
+}
+
+// botTurn lets a computer player fire until the turn passes back or
+// the game ends, and returns the shots it fired. It does nothing if
+// the player is not a computer or it is not its turn. The caller must
+// hold the game's Mu.
+func botTurn(pgame *models.Game, pbot *models.Player) ([]firedShot, error) {
+	fired := []firedShot{}
+	ptarget := pgame.Players[pbot.OpponentID]
+	for pbot.Bot && pgame.Status == models.GamePlaying && pgame.NextToPlay == pbot.ID {
+		strategy := ai.Lookup(pbot.Level)
+		result, err := pgame.Fire(pbot.ID, strategy.NextShot(ai.NewKnowledge(pbot, ptarget)))
+		if err != nil {
+			return fired, err
+		}
+		reportShot(pbot, ptarget, result, pgame.NextToPlay == pbot.ID)
+		fired = append(fired, firedShot{pbot, pbot.Shots[len(pbot.Shots)-1]})
+	}
+	return fired, nil
+}
+
+// gameOver keeps the replay of a game that has just ended, which
+// outlives the game in the store, and records its result. It is called
+// in the Update that ends the game, so that no one sees the game as
+// ended before its result is recorded. The caller must hold the game's
+// Mu.
+func (app *application) gameOver(pgame *models.Game) {
+	err := app.replays.Put(pgame.Replay())
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+	err = app.recordGame(pgame)
+	if err != nil {
+		app.errorLog.Print(err)
+	}
+}
+
+// recordGame records the result of an ended game in the statistics
+// and ratings of its players. The caller must hold the game's Mu.
+func (app *application) recordGame(pgame *models.Game) error {
+	result := pgame.Result()
+	if result == nil {
+		return nil
+	}
+	return app.stats.Record(result)
+}
+
+// recordResult is recordGame for a game that is not locked. The stats
+// store records a game only once, so a result that could not be
+// recorded when the game ended is recorded by a later call: when a
+// player leaves the game, when the game is removed, or when the server
+// restarts.
+func (app *application) recordResult(gameID string) error {
+	return app.games.View(gameID, func(pgame *models.Game) error {
+		return app.recordGame(pgame)
+	})
+}
+
+// firedShot is a shot together with the player who fired it
+type firedShot struct {
+	pshooter *models.Player
+	shot     models.Shot
+}
+
+// publishShots publishes the events of the shots fired, the ships
+// they sank and the end of the game. The caller must hold the game's Mu.
+func publishShots(pgame *models.Game, fired []firedShot) {
+	for _, f := range fired {
+		ev := models.Event{
+			Type:     models.EventShot,
+			PlayerID: f.pshooter.ID,
+			NickName: f.pshooter.NickName,
+			Shot:     f.shot,
+			Time:     f.shot.Time,
+		}
+		pgame.Events.Publish(ev)
+		if f.shot.Outcome == models.Sunk {
+			ev.Type = models.EventSunk
+			pgame.Events.Publish(ev)
+		}
+		if f.shot.GameOver {
+			ev.Type = models.EventGameOver
+			pgame.Events.Publish(ev)
+		}
+	}
+}
+
+// pageEvent is the data of a server sent event telling the page of
+// a player what happened in the game, so that it can update itself
+type pageEvent struct {
+	Seq      int        `json:"seq"`
+	Type     string     `json:"type"` // an EventType, or "reload" when the page has to be reloaded
+	By       string     `json:"by"`   // nickname of the player who did what the event is about
+	Mine     bool       `json:"mine"` // the player of the page did it
+	Seat     int        `json:"seat"` // 1 if the game's owner did it, 2 if the other player did
+	Turn     int        `json:"turn,omitempty"`
+	Time     string     `json:"time,omitempty"`
+	Square   string     `json:"square,omitempty"`
+	Outcome  string     `json:"outcome,omitempty"`
+	Class    string     `json:"class,omitempty"`
+	Wreck    []string   `json:"wreck,omitempty"`
+	Text     string     `json:"text,omitempty"`
+	Muted    bool       `json:"muted,omitempty"` // for "muted", the chat is muted now
+	Clock    *clockData `json:"clock,omitempty"` // the clocks now, for the players of a game with time limits
+	Count    int        `json:"count,omitempty"`
+	YourTurn bool       `json:"your_turn"`
+	Messages []string   `json:"messages"`
+}
+
+// newPageEvent gives what the page of playerID is told about the
+// event, along with the player's current messages and turn. Pages
+// of spectators have an empty playerID. The caller must hold the
+// game's Mu.
+func newPageEvent(pgame *models.Game, playerID string, ev models.Event) pageEvent {
+	pev := pageEvent{
+		Seq:      ev.Seq,
+		Type:     string(ev.Type),
+		By:       ev.NickName,
+		Mine:     ev.PlayerID != "" && ev.PlayerID == playerID,
+		Text:     ev.Text,
+		Count:    ev.Count,
+		YourTurn: pgame.Status == models.GamePlaying && pgame.NextToPlay == playerID,
+		Messages: []string{},
+	}
+	if pplayer, ok := pgame.Players[playerID]; ok {
+		// copied, as fire reuses the slice once the lock is released
+		pev.Messages = append(pev.Messages, pplayer.StatusMsgs...)
+		pev.Clock = newClockData(pgame, playerID)
+	}
+	switch ev.PlayerID {
+	case "":
+	case pgame.Owner:
+		pev.Seat = 1
+	default:
+		pev.Seat = 2
+	}
+	switch ev.Type {
+	case models.EventShot, models.EventSunk, models.EventGameOver:
+		pev.Turn = ev.Shot.Turn
+		pev.Time = ev.Shot.Time.Format("15:04:05")
+		pev.Square = ev.Shot.Square()
+		pev.Outcome = ev.Shot.Outcome.String()
+		pev.Class = ev.Shot.Class
+	}
+	switch ev.Type {
+	case models.EventChat:
+		pev.Time = ev.Time.Format("15:04:05")
+		// the chat is between the players only
+		if _, ok := pgame.Players[playerID]; !ok {
+			pev.Text = ""
+		}
+	case models.EventMuted:
+		pev.Muted = pgame.ChatMuted
+	}
+	if ev.Type == models.EventSunk {
+		for _, square := range ev.Shot.Wreck {
+			pev.Wreck = append(pev.Wreck, square.String())
+		}
+	}
+	return pev
+}
+
+// reportShot adds the messages telling the shooter and the target
+// what a shot did. again tells that the shooter fires again. Computer
+// players do not read messages, so none are added for them.
+func reportShot(pshooter, ptarget *models.Player, result models.ShotResult, again bool) {
+	shooterMsgs := []string{}
+	targetMsgs := []string{}
+	switch result.Outcome {
+	case models.Miss:
+		shooterMsgs = append(shooterMsgs, "You missed.")
+		if pshooter.Bot {
+			targetMsgs = append(targetMsgs, fmt.Sprintf("%s fired at %s and missed.", pshooter.NickName, result.Square()))
+		} else {
+			targetMsgs = append(targetMsgs, fmt.Sprintf("%s has missed. No casualty.", pshooter.NickName))
+		}
+	case models.Hit, models.Sunk:
+		shooterMsgs = append(shooterMsgs, "You have HIT a ship.")
+		if pshooter.Bot {
+			targetMsgs = append(targetMsgs, fmt.Sprintf("You have been hit at %s.", result.Square()))
+		} else {
+			targetMsgs = append(targetMsgs, "You have been hit.")
+		}
+	}
+	if result.Outcome == models.Sunk {
+		shooterMsgs = append(shooterMsgs, "You have destroyed a "+result.Class+".")
+		targetMsgs = append(targetMsgs, "You have lost a "+result.Class+".")
+	}
+
+	switch {
+	case result.GameOver:
+		shooterMsgs = append(shooterMsgs, "You have destroyed all your opponent's ships.", "You are the WINNER!")
+		targetMsgs = append(targetMsgs, "You have lost  all your ships", "You have lost the game.")
+	case again:
+		shooterMsgs = append(shooterMsgs, "You get another shot.")
+		targetMsgs = append(targetMsgs, fmt.Sprintf("%s gets another shot.", pshooter.NickName))
+	case !ptarget.Bot:
+		shooterMsgs = append(shooterMsgs, fmt.Sprintf("Waiting for %s to play.", ptarget.NickName))
+		targetMsgs = append(targetMsgs, "Your turn to play.")
+	}
+
+	if !pshooter.Bot {
+		pshooter.StatusMsgs = append(pshooter.StatusMsgs, shooterMsgs...)
+	}
+	if !ptarget.Bot {
+		ptarget.StatusMsgs = append(ptarget.StatusMsgs, targetMsgs...)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// A player's page of a game is open while its event stream is. A player
+// of a game in progress who has no page open for AwayNotice is said to
+// have gone, and loses the game after the grace period set by -abandon
+// unless the page is opened again. Games against the computer are not
+// watched, as no one waits for the player.
+
+// pageOpened records that a page of playerID opened the event stream
+// of the game, and tells the opponent if the player had gone. An
+// opponent with no page open is checked on in turn.
+func (app *application) pageOpened(gameID, playerID string) {
+	err := app.games.Update(gameID, func(pgame *models.Game) error {
+		pplayer, ok := pgame.Players[playerID]
+		if !ok {
+			return nil
+		}
+		popponent, ok := pgame.Players[pplayer.OpponentID]
+		if ok && popponent.Bot {
+			return nil
+		}
+		back := pgame.PageOpened(playerID)
+		if !ok {
+			// the game waits for the second player
+			return nil
+		}
+		if back && pgame.Status == models.GamePlaying {
+			// the notice that the player has gone no longer holds
+			gone := fmt.Sprintf("%s has disconnected.", pplayer.NickName)
+			msgs := []string{}
+			for _, msg := range popponent.StatusMsgs {
+				if !strings.HasPrefix(msg, gone) {
+					msgs = append(msgs, msg)
+				}
+			}
+			popponent.StatusMsgs = append(msgs, fmt.Sprintf("%s is back.", pplayer.NickName))
+			pgame.Events.Publish(models.Event{
+				Type:     models.EventBack,
+				PlayerID: playerID,
+				NickName: pplayer.NickName,
+			})
+		}
+
+		// the opponent may have gone while no one was there to win,
+		// or before the game started
+		if ppresence, ok := pgame.Presence[popponent.ID]; ok && ppresence.Pages == 0 && ppresence.Timer == nil {
+			ppresence.Since = time.Now()
+			app.awaitReturn(pgame, popponent.ID, AwayNotice)
+		}
+		return nil
+	})
+	if err != nil && err != models.ErrNoGame {
+		app.errorLog.Print(err)
+	}
+}
+
+// pageClosed records that the event stream of a page of playerID
+// closed, and checks on the player if no page of the game is left open
+func (app *application) pageClosed(gameID, playerID string) {
+	err := app.games.Update(gameID, func(pgame *models.Game) error {
+		if !pgame.PageClosed(playerID, time.Now()) {
+			return nil
+		}
+		app.awaitReturn(pgame, playerID, AwayNotice)
+		return nil
+	})
+	if err != nil && err != models.ErrNoGame {
+		app.errorLog.Print(err)
+	}
+}
+
+// awaitReturn checks on playerID, who has no page of the game open,
+// after delay. The caller must hold the game's Mu.
+func (app *application) awaitReturn(pgame *models.Game, playerID string, delay time.Duration) {
+	ppresence := pgame.Presence[playerID]
+	if ppresence.Timer != nil {
+		ppresence.Timer.Stop()
+	}
+	gameID := pgame.ID
+	ppresence.Timer = time.AfterFunc(delay, func() {
+		app.checkAway(gameID, playerID)
+	})
+}
+
+// checkAway tells the opponent that playerID has gone, once the player
+// has had no page of the game open for AwayNotice, and ends the game
+// as lost by the player once the grace period set by -abandon has
+// passed. The game is left as it is while the opponent has no page
+// open either: the first of them back waits for the other.
+func (app *application) checkAway(gameID, playerID string) {
+	err := app.games.Update(gameID, func(pgame *models.Game) error {
+		now := time.Now()
+		away, ok := pgame.AwayFor(playerID, now)
+		if !ok {
+			return nil
+		}
+		ppresence := pgame.Presence[playerID]
+		ppresence.Timer = nil
+		if pgame.Status != models.GamePlaying {
+			return nil
+		}
+		pplayer := pgame.Players[playerID]
+		popponent := pgame.Players[pplayer.OpponentID]
+		if _, gone := pgame.AwayFor(popponent.ID, now); gone {
+			return nil
+		}
+
+		left := app.cfg.abandonGrace - away
+		if left <= 0 {
+			return app.forfeit(pgame, playerID, forfeitAbandoned)
+		}
+		if !ppresence.Away {
+			ppresence.Away = true
+			popponent.StatusMsgs = append(popponent.StatusMsgs, fmt.Sprintf("%s has disconnected. You win the game if %s is not back within %s.",
+				pplayer.NickName, pplayer.NickName, left.Round(time.Second)))
+			pgame.Events.Publish(models.Event{
+				Type:     models.EventAway,
+				PlayerID: playerID,
+				NickName: pplayer.NickName,
+			})
+		}
+		app.awaitReturn(pgame, playerID, left)
+		return nil
+	})
+	if err != nil && err != models.ErrNoGame {
+		app.errorLog.Print(err)
+	}
+}
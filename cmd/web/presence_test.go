@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/forms"
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// awayGame starts a game between alice and bobby, both with a page
+// open, and subscribes to its events. It returns the game and the IDs
+// of alice and bobby.
+func awayGame(t *testing.T, app *application) (string, string, string, chan models.Event) {
+	t.Helper()
+	pgame, aliceID, err := app.newGame(forms.New(mustFleet(t, "alice")), models.Rulesets[0], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbobby, err := app.addPlayer(pgame.ID, forms.New(mustFleet(t, "bobby")), models.Rulesets[0], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.pageOpened(pgame.ID, aliceID)
+	app.pageOpened(pgame.ID, pbobby.ID)
+	var events chan models.Event
+	app.games.View(pgame.ID, func(pgame *models.Game) error {
+		_, events, _ = pgame.Events.Subscribe(pgame.Events.Seq())
+		return nil
+	})
+	return pgame.ID, aliceID, pbobby.ID, events
+}
+
+// nextEvent returns the next event of the type, or fails the test if
+// none comes within a second
+func nextEvent(t *testing.T, events chan models.Event, typ models.EventType) models.Event {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == typ {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", typ)
+		}
+	}
+}
+
+func TestAbandon(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.abandonGrace = 50 * time.Millisecond
+	gameID, aliceID, bobbyID, events := awayGame(t, app)
+
+	// the check due AwayNotice after the page closed, which goes on
+	// checking until the grace period has passed
+	app.pageClosed(gameID, bobbyID)
+	app.checkAway(gameID, bobbyID)
+	if ev := nextEvent(t, events, models.EventAway); ev.PlayerID != bobbyID {
+		t.Errorf("got away event for %s; want bobby", ev.NickName)
+	}
+	ev := nextEvent(t, events, models.EventForfeit)
+	if ev.PlayerID != bobbyID || ev.Text != forfeitAbandoned {
+		t.Errorf("got forfeit by %s, %q; want bobby, %q", ev.NickName, ev.Text, forfeitAbandoned)
+	}
+
+	app.games.View(gameID, func(pgame *models.Game) error {
+		if pgame.Status != models.GameEnded || pgame.Forfeited != bobbyID || pgame.Wins[aliceID] != 1 {
+			t.Errorf("got status %d, forfeited by %q, wins %v; want alice winning", pgame.Status, pgame.Forfeited, pgame.Wins)
+		}
+		return nil
+	})
+}
+
+func TestAbandonBackInTime(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.abandonGrace = 50 * time.Millisecond
+	gameID, _, bobbyID, events := awayGame(t, app)
+
+	app.pageClosed(gameID, bobbyID)
+	app.checkAway(gameID, bobbyID)
+	nextEvent(t, events, models.EventAway)
+	app.pageOpened(gameID, bobbyID)
+	nextEvent(t, events, models.EventBack)
+
+	time.Sleep(2 * app.cfg.abandonGrace)
+	app.games.View(gameID, func(pgame *models.Game) error {
+		if pgame.Status != models.GamePlaying {
+			t.Errorf("got status %d, forfeited by %q; want the game in progress", pgame.Status, pgame.Forfeited)
+		}
+		return nil
+	})
+}
+
+func TestAbandonBothGone(t *testing.T) {
+	app := newTestApplication(t)
+	app.cfg.abandonGrace = 50 * time.Millisecond
+	gameID, aliceID, bobbyID, _ := awayGame(t, app)
+
+	// no one is there to win the game
+	app.pageClosed(gameID, aliceID)
+	app.pageClosed(gameID, bobbyID)
+	time.Sleep(2 * app.cfg.abandonGrace)
+	app.checkAway(gameID, bobbyID)
+	app.games.View(gameID, func(pgame *models.Game) error {
+		if pgame.Status != models.GamePlaying {
+			t.Errorf("got status %d, forfeited by %q; want the game in progress", pgame.Status, pgame.Forfeited)
+		}
+		return nil
+	})
+}
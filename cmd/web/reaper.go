@@ -0,0 +1,88 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// reapGames removes the games idle for too long every ReapInterval.
+// It runs for as long as the server does.
+func (app *application) reapGames() {
+	ticker := time.NewTicker(ReapInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		app.reap(now)
+	}
+}
+
+// gameTTL returns how long a game with the status may stay idle
+func (app *application) gameTTL(status int) time.Duration {
+	switch status {
+	case models.GameStarting:
+		return app.cfg.waitingTTL
+	case models.GamePlaying:
+		return app.cfg.playingTTL
+	}
+	return app.cfg.endedTTL
+}
+
+// reap removes the games that have been idle for longer than the TTL
+// of their status. The pages still open on a game are told before it
+// is removed, and the result of an ended game is recorded first, all
+// under the game's Mu so that a shot or a join cannot come in between.
+func (app *application) reap(now time.Time) {
+	for _, pgame := range app.games.List() {
+		gameID := pgame.ID
+		expired, listed := false, false
+		var idle time.Duration
+		var status int
+		err := app.games.DeleteIf(gameID, func(pgame *models.Game) bool {
+			idle = pgame.IdleFor(now)
+			status = pgame.Status
+			if idle < app.gameTTL(status) {
+				return false
+			}
+			expired = true
+			listed = pgame.Listed()
+			stopTimers(pgame)
+			pgame.Events.Publish(models.Event{Type: models.EventExpired})
+			err := app.recordGame(pgame)
+			if err != nil {
+				app.errorLog.Print(err)
+			}
+			return true
+		})
+		if err == models.ErrNoGame {
+			// deleted since the list was made
+			continue
+		}
+		if err != nil {
+			app.errorLog.Print(err)
+			continue
+		}
+		if !expired {
+			continue
+		}
+
+		if listed {
+			app.lobbyChanged(gameID)
+		}
+		app.infoLog.Printf("Removed %s game %s, idle for %s.", apiStatus[status], gameID, idle.Round(time.Second))
+	}
+}
+
+// stopTimers stops the clock of the game and the checks on players
+// who have closed its page. The caller must hold the game's Mu.
+func stopTimers(pgame *models.Game) {
+	if pgame.Timer != nil {
+		pgame.Timer.Stop()
+		pgame.Timer = nil
+	}
+	for _, ppresence := range pgame.Presence {
+		if ppresence.Timer != nil {
+			ppresence.Timer.Stop()
+			ppresence.Timer = nil
+		}
+	}
+}
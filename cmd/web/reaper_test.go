@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+func TestReap(t *testing.T) {
+	app := newTestApplication(t)
+	ts := httptest.NewServer(app.router())
+	defer ts.Close()
+
+	var game apiJoined
+	code := apiCall(t, ts, "/api/v1/games", "", apiNewPlayer{Username: "alice", AutoPlace: true, Clock: "shot30"}, &game)
+	if code != http.StatusCreated {
+		t.Fatalf("create returned %d", code)
+	}
+	var joined apiJoined
+	code = apiCall(t, ts, "/api/v1/games/"+game.GameID+"/join", "", apiNewPlayer{Username: "bobby", AutoPlace: true}, &joined)
+	if code != http.StatusCreated {
+		t.Fatalf("join returned %d", code)
+	}
+	var events chan models.Event
+	app.games.View(game.GameID, func(pgame *models.Game) error {
+		_, events, _ = pgame.Events.Subscribe(pgame.Events.Seq())
+		return nil
+	})
+
+	// not idle for long enough
+	app.reap(time.Now().Add(app.cfg.playingTTL / 2))
+	if _, ok := app.games.Get(game.GameID); !ok {
+		t.Fatal("a game in progress was removed before its TTL")
+	}
+
+	app.reap(time.Now().Add(app.cfg.playingTTL + time.Minute))
+	if _, ok := app.games.Get(game.GameID); ok {
+		t.Fatal("an idle game was not removed")
+	}
+	if ev := <-events; ev.Type != models.EventExpired {
+		t.Errorf("got a %s event; want expired", ev.Type)
+	}
+	var reply apiErrorReply
+	if code := apiCall(t, ts, "/api/v1/games/"+game.GameID, game.Token, nil, &reply); code != http.StatusNotFound {
+		t.Errorf("got %d for the removed game; want %d", code, http.StatusNotFound)
+	}
+}
@@ -12,17 +12,52 @@ func (app *application) router() http.Handler {
 
 	dynamicMiddleware := alice.New(app.session.Enable)
 
+	apiMiddleware := alice.New(app.requireToken)
+
 	mux := pat.New()
 	mux.Get("/", dynamicMiddleware.ThenFunc(app.home))
-	mux.Get("/sse", dynamicMiddleware.ThenFunc(app.handleSse))
+	mux.Get("/sse", alice.New(keepWriter).Extend(dynamicMiddleware).ThenFunc(app.handleSse))
 	mux.Get("/start", dynamicMiddleware.ThenFunc(app.startGameForm))
 	mux.Post("/start", dynamicMiddleware.ThenFunc(app.startGame))
 	mux.Get("/join/:gameid", dynamicMiddleware.Append(app.gameExists, app.canJoin).ThenFunc(app.joinGameForm))
 	mux.Post("/join/:gameid", dynamicMiddleware.Append(app.gameExists, app.canJoin).ThenFunc(app.joinGame))
+	mux.Post("/api/v1/games", http.HandlerFunc(app.apiCreateGame))
+	mux.Post("/api/v1/games/:gameid/join", http.HandlerFunc(app.apiJoinGame))
+	mux.Get("/api/v1/games/:gameid", apiMiddleware.ThenFunc(app.apiGame))
+	mux.Post("/api/v1/games/:gameid/shots", apiMiddleware.ThenFunc(app.apiFire))
+	mux.Post("/api/v1/games/:gameid/chat", apiMiddleware.ThenFunc(app.apiChat))
+	mux.Post("/api/v1/games/:gameid/resign", apiMiddleware.ThenFunc(app.apiResign))
+	mux.Post("/api/v1/games/:gameid/rematch", apiMiddleware.ThenFunc(app.apiRematch))
+	mux.Get("/api/v1/leaderboard", http.HandlerFunc(app.apiLeaderboard))
+	mux.Get("/match", dynamicMiddleware.ThenFunc(app.waitMatch))
+	mux.Post("/match/cancel", dynamicMiddleware.ThenFunc(app.cancelMatch))
+	mux.Get("/match/sse", alice.New(keepWriter).Extend(dynamicMiddleware).ThenFunc(app.matchSse))
+	mux.Get("/lobby", dynamicMiddleware.ThenFunc(app.showLobby))
+	mux.Get("/lobby/sse", alice.New(keepWriter).Extend(dynamicMiddleware).ThenFunc(app.lobbySse))
+	mux.Get("/leaderboard", dynamicMiddleware.ThenFunc(app.showLeaderboard))
+	mux.Get("/user/signup", dynamicMiddleware.ThenFunc(app.signupUserForm))
+	mux.Post("/user/signup", dynamicMiddleware.ThenFunc(app.signupUser))
+	mux.Get("/user/login", dynamicMiddleware.ThenFunc(app.loginUserForm))
+	mux.Post("/user/login", dynamicMiddleware.ThenFunc(app.loginUser))
+	mux.Post("/user/logout", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.logoutUser))
+	mux.Get("/user/profile", dynamicMiddleware.Append(app.requireAuthentication).ThenFunc(app.userProfile))
+	mux.Get("/replay/import", dynamicMiddleware.ThenFunc(app.importReplayForm))
+	mux.Post("/replay/import", dynamicMiddleware.ThenFunc(app.importReplay))
+	mux.Get("/replay/:gameid", dynamicMiddleware.ThenFunc(app.replayGame))
+	mux.Get("/replay/:gameid/export", http.HandlerFunc(app.exportReplay))
+	mux.Get("/watch/:gameid", dynamicMiddleware.Append(app.gameExists, app.canWatch).ThenFunc(app.watchGame))
+	mux.Get("/watch/:gameid/sse", alice.New(keepWriter).Extend(dynamicMiddleware).Append(app.gameExists, app.canWatch).ThenFunc(app.watchSse))
+	mux.Post("/:gameid/spectators", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.allowSpectators))
+	mux.Post("/:gameid/private", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.makePrivate))
+	mux.Post("/:gameid/chat", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.sendChat))
+	mux.Post("/:gameid/mute", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.muteChat))
+	mux.Post("/:gameid/rematch", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.rematchGame))
+	mux.Post("/:gameid/resign", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.resignGame))
+	mux.Post("/:gameid/leave", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.leaveGame))
 	mux.Get("/:gameid", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.playGameForm))
 	mux.Post("/:gameid", dynamicMiddleware.Append(app.gameExists, app.belongsToGame).ThenFunc(app.playGame))
 
-	fileServer := http.FileServer(http.Dir("./ui/static/"))
+	fileServer := http.FileServer(http.Dir(app.cfg.staticDir))
 	mux.Get("/static/", http.StripPrefix("/static", fileServer))
 
 	return standardMiddleware.Then(mux)
@@ -1,20 +1,194 @@
 package main
 
 import (
+	"fmt"
 	"html/template"
 	"path/filepath"
+	"time"
 
+	"github.com/rjpgt/battleship/pkg/ai"
 	"github.com/rjpgt/battleship/pkg/forms"
 	"github.com/rjpgt/battleship/pkg/models"
 )
 
 type templateData struct {
-	Flash    string
-	Form     *forms.Form
-	GameID   string
-	Opponent string
-	Player   *models.Player
-	Status   int
+	Chat        *chatData  // nil when playing the computer
+	Clock       *clockData // nil for a game without time limits
+	Clocks      []models.TimeControl
+	Flash       string
+	Form        *forms.Form
+	GameID      string
+	Leaderboard []*models.Stats
+	Levels      []ai.Level
+	Lobby       []*lobbyGame
+	Moves       []models.Move
+	Opponent    string
+	Owner       bool // the player created the game
+	Player      *models.Player
+	Players     []*models.Player
+	Private     bool         // the game is not listed in the lobby
+	Queued      int          // number of players waiting for an opponent
+	Ranked      bool         // the game moves the ratings of its players
+	Rematch     *rematchData // for a game that has ended
+	Replay      []replayMove
+	Rules       models.Ruleset
+	Rulesets    []models.Ruleset
+	Seeker      *models.Seeker // the player waiting for an opponent
+	Seq         int            // number of the last game or lobby event shown on the page
+	Sizes       []int
+	SortBy      string        // what the leaderboard is sorted by
+	Stats       *models.Stats // of the logged in user
+	Spectators  bool          // spectators may watch the game
+	Status      int
+	User        *models.User // the logged in user, nil for a guest
+	Watching    int          // number of spectators
+	YourTurn    bool
+}
+
+// rematchData is what the page of an ended game shows about a rematch
+// and the series the game is part of
+type rematchData struct {
+	Asked         bool // the player asked for a rematch
+	OpponentAsked bool
+	OpponentLeft  bool
+	NewSeries     bool // the rematch starts a series, whose length the player may choose
+	BestOf        int
+	Score         []seriesScore // the player's first, empty for a single game
+	SeriesLengths []int
+	Winner        string // who won the series, once it is over
+}
+
+// chatData is what the page of a player shows of the chat
+type chatData struct {
+	Messages  []models.ChatMessage
+	Muted     bool
+	MaxLength int
+}
+
+// clockData is what the play page shows of the clocks. The times
+// are in milliseconds, as of when the page was made.
+type clockData struct {
+	Label    string `json:"-"`
+	AutoFire bool   `json:"-"`
+	Left     int64  `json:"left"` // for the player to play to fire
+	PerGame  bool   `json:"-"`
+	Mine     int64  `json:"mine"`   // on the player's game clock
+	Theirs   int64  `json:"theirs"` // on the opponent's game clock
+}
+
+// newClockData gives what the page of playerID shows of the clocks,
+// or nil if the game has no time limits. The caller must hold the
+// game's Mu.
+func newClockData(pgame *models.Game, playerID string) *clockData {
+	if !pgame.Clock.Limited() {
+		return nil
+	}
+	now := time.Now()
+	cd := &clockData{
+		Label:    pgame.Clock.Label,
+		AutoFire: pgame.Clock.AutoFire,
+		PerGame:  pgame.Clock.PerGame > 0,
+	}
+	left, _ := pgame.TimeLeft(now)
+	cd.Left = milliseconds(left)
+	if cd.PerGame {
+		cd.Mine = milliseconds(pgame.GameClock(playerID, now))
+		if pplayer, ok := pgame.Players[playerID]; ok {
+			cd.Theirs = milliseconds(pgame.GameClock(pplayer.OpponentID, now))
+		}
+	}
+	return cd
+}
+
+// milliseconds gives a duration in whole milliseconds
+func milliseconds(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
+
+// seriesScore is the number of games of a series a player won
+type seriesScore struct {
+	NickName string
+	Wins     int
+}
+
+// newRematchData gives what the page of playerID shows about a rematch
+// of the ended game. The caller must hold the game's Mu.
+func newRematchData(pgame *models.Game, playerID string) *rematchData {
+	pplayer := pgame.Players[playerID]
+	rd := &rematchData{
+		BestOf:        pgame.BestOf,
+		NewSeries:     pgame.PrevID == "" || pgame.SeriesOver(),
+		SeriesLengths: models.SeriesLengths,
+	}
+	_, rd.Asked = pgame.Rematch[playerID]
+	_, rd.OpponentAsked = pgame.Rematch[pplayer.OpponentID]
+	popponent, ok := pgame.Players[pplayer.OpponentID]
+	rd.OpponentLeft = !ok
+	if pgame.PrevID == "" || !ok {
+		return rd
+	}
+	rd.Score = []seriesScore{
+		{pplayer.NickName, pgame.Wins[playerID]},
+		{popponent.NickName, pgame.Wins[popponent.ID]},
+	}
+	if pgame.SeriesOver() {
+		for _, score := range rd.Score {
+			if score.Wins > pgame.BestOf/2 {
+				rd.Winner = score.NickName
+			}
+		}
+	}
+	return rd
+}
+
+// replayMove is a shot of a replay as the replay page steps through it.
+// Seat is the index in the replay's Players of the player who fired.
+type replayMove struct {
+	Seat     int      `json:"seat"`
+	By       string   `json:"by"`
+	Square   string   `json:"square"`
+	Outcome  string   `json:"outcome"`
+	Class    string   `json:"class,omitempty"`
+	Wreck    []string `json:"wreck,omitempty"`
+	GameOver bool     `json:"game_over"`
+}
+
+// square gives the square at row, col in the notation of the forms
+func square(row, col int) string {
+	return models.Coord{Row: row, Col: col}.String()
+}
+
+// age tells how long ago t was, roughly
+func age(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case t.IsZero():
+		return ""
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%d h ago", int(d.Hours()))
+	}
+}
+
+// clock shows milliseconds as minutes and seconds
+func clock(ms int64) string {
+	seconds := (ms + 999) / 1000
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// inc counts from 1 where a template ranges from 0
+func inc(i int) int {
+	return i + 1
+}
+
+var functions = template.FuncMap{
+	"age":    age,
+	"clock":  clock,
+	"inc":    inc,
+	"square": square,
 }
 
 func newTemplateCache(dir string) (map[string]*template.Template, error) {
@@ -27,7 +201,7 @@ func newTemplateCache(dir string) (map[string]*template.Template, error) {
 	for _, page := range pages {
 		name := filepath.Base(page)
 
-		ts, err := template.New(name).ParseFiles(page)
+		ts, err := template.New(name).Funcs(functions).ParseFiles(page)
 		if err != nil {
 			return nil, err
 		}
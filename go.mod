@@ -6,4 +6,5 @@ require (
 	github.com/bmizerany/pat v0.0.0-20170815010413-6226ea591a40
 	github.com/golangcollege/sessions v1.1.0
 	github.com/justinas/alice v0.0.0-20171023064455-03f45bd4b7da
+	golang.org/x/crypto v0.0.0-20181009213950-7c1a557ab941
 )
@@ -0,0 +1,89 @@
+// Package ai has the firing strategies of the computer opponent
+package ai
+
+import (
+	"math/rand"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// Knowledge is what a player knows about the opponent's board
+type Knowledge struct {
+	// Size is the number of rows and columns of the board
+	Size int
+	// Shots is the player's ShotsBoard: "" for a square not fired
+	// at, "splash" for a miss and "hit_bomb" for a hit
+	Shots [][]string
+	// Sunk marks the squares of the ships known to be sunk
+	Sunk map[models.Coord]bool
+	// Remaining has the sizes of the ships still afloat
+	Remaining []int
+}
+
+// NewKnowledge collects what pshooter knows about ptarget's board.
+// The caller must hold the game's Mu.
+func NewKnowledge(pshooter, ptarget *models.Player) Knowledge {
+	k := Knowledge{
+		Size:  len(pshooter.ShotsBoard),
+		Shots: pshooter.ShotsBoard,
+		Sunk:  map[models.Coord]bool{},
+	}
+	for _, shot := range pshooter.Shots {
+		for _, square := range shot.Wreck {
+			k.Sunk[square] = true
+		}
+	}
+	for _, pship := range ptarget.Ships {
+		k.Remaining = append(k.Remaining, len(pship.Squares))
+	}
+	return k
+}
+
+// unknown tells if a square is on the board and not fired at yet
+func (k *Knowledge) unknown(c models.Coord) bool {
+	return c.On(k.Size) && k.Shots[c.Row][c.Col] == ""
+}
+
+// openHit tells if a square is a hit on a ship not yet sunk
+func (k *Knowledge) openHit(c models.Coord) bool {
+	return c.On(k.Size) && k.Shots[c.Row][c.Col] == "hit_bomb" && !k.Sunk[c]
+}
+
+// Strategy picks the next square a computer player fires at.
+// The square returned must not have been fired at.
+type Strategy interface {
+	NextShot(k Knowledge) models.Coord
+}
+
+// Level is a difficulty level offered on the start form
+type Level struct {
+	Name     string
+	Label    string
+	Strategy Strategy
+}
+
+// Levels lists the difficulty levels from the easiest
+var Levels = []Level{
+	{"easy", "Easy (random)", Random{}},
+	{"medium", "Medium (hunt and target)", HuntTarget{}},
+	{"hard", "Hard (probability)", Density{}},
+}
+
+// Lookup returns the strategy of a level. Unknown
+// levels get the strategy of the easiest level.
+func Lookup(name string) Strategy {
+	for _, level := range Levels {
+		if level.Name == name {
+			return level.Strategy
+		}
+	}
+	return Levels[0].Strategy
+}
+
+// pick returns one of the squares at random
+func pick(squares []models.Coord) models.Coord {
+	if len(squares) == 0 {
+		return models.Coord{}
+	}
+	return squares[rand.Intn(len(squares))]
+}
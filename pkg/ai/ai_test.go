@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// knowledge reads a board drawn one row per string: "." for a square
+// not fired at, "o" for a miss, "x" for a hit on a ship afloat and
+// "s" for a square of a sunk ship
+func knowledge(remaining []int, rows ...string) Knowledge {
+	k := Knowledge{
+		Size:      len(rows),
+		Shots:     make([][]string, len(rows)),
+		Sunk:      map[models.Coord]bool{},
+		Remaining: remaining,
+	}
+	for row, line := range rows {
+		k.Shots[row] = make([]string, len(line))
+		for col, square := range line {
+			switch square {
+			case 'o':
+				k.Shots[row][col] = "splash"
+			case 'x':
+				k.Shots[row][col] = "hit_bomb"
+			case 's':
+				k.Shots[row][col] = "hit_bomb"
+				k.Sunk[models.Coord{Row: row, Col: col}] = true
+			}
+		}
+	}
+	return k
+}
+
+// tries is how many times a strategy is asked for a shot, as it picks
+// at random among the squares it finds best
+const tries = 200
+
+func TestHuntTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		k    Knowledge
+		want []models.Coord // any of them
+	}{
+		{
+			name: "line on a row",
+			k:    knowledge([]int{3}, "......", "......", "..xx..", "......", "......", "......"),
+			want: []models.Coord{{Row: 2, Col: 1}, {Row: 2, Col: 4}},
+		},
+		{
+			name: "line on a column, one end missed",
+			k:    knowledge([]int{3}, "......", "...x..", "...x..", "...o..", "......", "......"),
+			want: []models.Coord{{Row: 0, Col: 3}},
+		},
+		{
+			name: "single hit",
+			k:    knowledge([]int{2}, "......", "......", "..x...", "......", "......", "......"),
+			want: []models.Coord{{Row: 1, Col: 2}, {Row: 3, Col: 2}, {Row: 2, Col: 1}, {Row: 2, Col: 3}},
+		},
+		{
+			name: "hit in a corner next to a miss",
+			k:    knowledge([]int{2}, "xo....", "......", "......", "......", "......", "......"),
+			want: []models.Coord{{Row: 1, Col: 0}},
+		},
+		{
+			name: "hit next to a sunk ship",
+			k:    knowledge([]int{2}, "ss....", ".x....", "......", "......", "......", "......"),
+			want: []models.Coord{{Row: 1, Col: 0}, {Row: 1, Col: 2}, {Row: 2, Col: 1}},
+		},
+		{
+			name: "hunt past sunk ships and misses",
+			k:    knowledge([]int{2}, "sss.", "o.o.", "oooo", "o.o."),
+			want: []models.Coord{{Row: 1, Col: 1}, {Row: 1, Col: 3}, {Row: 3, Col: 1}, {Row: 3, Col: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tries; i++ {
+				got := HuntTarget{}.NextShot(tt.k)
+				if !contains(tt.want, got) {
+					t.Fatalf("fired at %v; want one of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDensity(t *testing.T) {
+	tests := []struct {
+		name string
+		k    Knowledge
+		want []models.Coord // any of them
+	}{
+		{
+			name: "line of hits",
+			k:    knowledge([]int{3}, "......", "......", "..xx..", "......", "......", "......"),
+			want: []models.Coord{{Row: 2, Col: 1}, {Row: 2, Col: 4}},
+		},
+		{
+			name: "most placements in the room left",
+			k:    knowledge([]int{2}, "oooo", "ssso", "oooo", "...o"),
+			want: []models.Coord{{Row: 3, Col: 1}},
+		},
+		{
+			name: "hit beside a sunk ship",
+			k:    knowledge([]int{2}, "o.o", "sxs", "ooo"),
+			want: []models.Coord{{Row: 0, Col: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tries; i++ {
+				got := Density{}.NextShot(tt.k)
+				if !contains(tt.want, got) {
+					t.Fatalf("fired at %v; want one of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestNeverFiredAt checks on boards of random misses, hits and sunk
+// ships that no strategy fires at a square already fired at
+func TestNeverFiredAt(t *testing.T) {
+	marks := []byte(".....oxs")
+	for i := 0; i < tries; i++ {
+		rows := make([]string, 8)
+		for row := range rows {
+			line := make([]byte, 8)
+			for col := range line {
+				line[col] = marks[rand.Intn(len(marks))]
+			}
+			rows[row] = string(line)
+		}
+		k := knowledge([]int{5, 4, 3, 3, 2}, rows...)
+		if len(free(k)) == 0 {
+			continue
+		}
+		for _, level := range Levels {
+			got := level.Strategy.NextShot(k)
+			if !k.unknown(got) {
+				t.Fatalf("%s fired at %v on\n%s", level.Name, got, strings.Join(rows, "\n"))
+			}
+		}
+	}
+}
+
+func contains(squares []models.Coord, c models.Coord) bool {
+	for _, square := range squares {
+		if square == c {
+			return true
+		}
+	}
+	return false
+}
+
+// free returns the squares not fired at
+func free(k Knowledge) []models.Coord {
+	squares := []models.Coord{}
+	for row := 0; row < k.Size; row++ {
+		for col := 0; col < k.Size; col++ {
+			if c := (models.Coord{Row: row, Col: col}); k.unknown(c) {
+				squares = append(squares, c)
+			}
+		}
+	}
+	return squares
+}
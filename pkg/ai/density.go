@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// Density counts, for every square not fired at, the ways the ships
+// still afloat could be placed over it and fires at the square with
+// the highest count. Placements over a miss or a sunk ship are not
+// possible. While there are hits on ships not yet sunk only the
+// placements through those hits are counted, weighted by the number
+// of hits they pass through.
+type Density struct{}
+
+// NextShot picks the square most likely to hold a ship
+func (Density) NextShot(k Knowledge) models.Coord {
+	targeting := false
+	for row := 0; row < k.Size && !targeting; row++ {
+		for col := 0; col < k.Size; col++ {
+			if k.openHit(models.Coord{Row: row, Col: col}) {
+				targeting = true
+				break
+			}
+		}
+	}
+
+	scores := map[models.Coord]int{}
+	for _, size := range k.Remaining {
+		for _, d := range directions[:2] {
+			for row := 0; row < k.Size; row++ {
+				for col := 0; col < k.Size; col++ {
+					hits := k.placement(row, col, d, size)
+					if hits < 0 || targeting && hits == 0 {
+						continue
+					}
+					for i := 0; i < size; i++ {
+						c := models.Coord{Row: row + i*d.Row, Col: col + i*d.Col}
+						if k.unknown(c) {
+							scores[c] += 1 + hits*10
+						}
+					}
+				}
+			}
+		}
+	}
+
+	best := []models.Coord{}
+	max := 0
+	for row := 0; row < k.Size; row++ {
+		for col := 0; col < k.Size; col++ {
+			c := models.Coord{Row: row, Col: col}
+			switch {
+			case scores[c] > max:
+				max = scores[c]
+				best = []models.Coord{c}
+			case scores[c] == max && max > 0:
+				best = append(best, c)
+			}
+		}
+	}
+	if len(best) == 0 {
+		return Random{}.NextShot(k)
+	}
+	return pick(best)
+}
+
+// placement returns the number of open hits a ship of the given size
+// starting at row, col and lying in direction d would cover, or -1
+// if the ship cannot lie there.
+func (k *Knowledge) placement(row, col int, d models.Coord, size int) int {
+	hits := 0
+	for i := 0; i < size; i++ {
+		c := models.Coord{Row: row + i*d.Row, Col: col + i*d.Col}
+		switch {
+		case k.unknown(c):
+		case k.openHit(c):
+			hits++
+		default:
+			return -1
+		}
+	}
+	return hits
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// HuntTarget fires on a checkerboard pattern until it hits a ship
+// (hunt) and then fires around the hit, following the line of the
+// hits once it knows whether the ship lies on a row or a column
+// (target), until the ship is sunk.
+type HuntTarget struct{}
+
+// directions are the steps to the squares right, below, left and above
+var directions = [4]models.Coord{{Row: 0, Col: 1}, {Row: 1, Col: 0}, {Row: 0, Col: -1}, {Row: -1, Col: 0}}
+
+// NextShot picks the next square to target or hunt
+func (HuntTarget) NextShot(k Knowledge) models.Coord {
+	inLine := []models.Coord{}
+	around := []models.Coord{}
+	for row := 0; row < k.Size; row++ {
+		for col := 0; col < k.Size; col++ {
+			if !k.openHit(models.Coord{Row: row, Col: col}) {
+				continue
+			}
+			for _, d := range directions {
+				next := models.Coord{Row: row + d.Row, Col: col + d.Col}
+				if !k.unknown(next) {
+					continue
+				}
+				around = append(around, next)
+				// extend a line of hits coming from the other side
+				if k.openHit(models.Coord{Row: row - d.Row, Col: col - d.Col}) {
+					inLine = append(inLine, next)
+				}
+			}
+		}
+	}
+	if len(inLine) > 0 {
+		return pick(inLine)
+	}
+	if len(around) > 0 {
+		return pick(around)
+	}
+
+	// every ship is at least 2 squares long, so one colour
+	// of the checkerboard is enough to find them all
+	hunt := []models.Coord{}
+	for row := 0; row < k.Size; row++ {
+		for col := 0; col < k.Size; col++ {
+			if c := (models.Coord{Row: row, Col: col}); (row+col)%2 == 0 && k.unknown(c) {
+				hunt = append(hunt, c)
+			}
+		}
+	}
+	if len(hunt) > 0 {
+		return pick(hunt)
+	}
+	return Random{}.NextShot(k)
+}
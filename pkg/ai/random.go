@@ -0,0 +1,21 @@
+package ai
+
+import (
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// Random fires at any square not fired at yet
+type Random struct{}
+
+// NextShot picks a random square
+func (Random) NextShot(k Knowledge) models.Coord {
+	free := []models.Coord{}
+	for row := 0; row < k.Size; row++ {
+		for col := 0; col < k.Size; col++ {
+			if c := (models.Coord{Row: row, Col: col}); k.unknown(c) {
+				free = append(free, c)
+			}
+		}
+	}
+	return pick(free)
+}
@@ -4,19 +4,10 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
 	"unicode/utf8"
-)
 
-// Regexp patterns for the various ships
-var (
-	BtlshipRX    = regexp.MustCompile(`^(\s*\d{2}\s*,){4}\s*\d{2}\s*$`)
-	CruiserRX    = regexp.MustCompile(`^(\s*\d{2}\s*,){3}\s*\d{2}\s*$`)
-	FrigateRX    = regexp.MustCompile(`^(\s*\d{2}\s*,){2}\s*\d{2}\s*$`)
-	DestroyerRX  = regexp.MustCompile(`^(\s*\d{2}\s*,){2}\s*\d{2}\s*$`)
-	PatrolboatRX = regexp.MustCompile(`^(\s*\d{2}\s*,){1}\s*\d{2}\s*$`)
-	FirePosRX    = regexp.MustCompile(`^\s*\d{2}\s*$`)
+	"github.com/rjpgt/battleship/pkg/models"
 )
 
 // Form embeds an anonymous url.Values object
@@ -95,31 +86,52 @@ func (f *Form) MatchesPattern(field string, pattern *regexp.Regexp) {
 	}
 }
 
+// Squares checks that a field lists count squares of a size x size board
+func (f *Form) Squares(field string, count, size int) {
+	value := f.Get(field)
+	if value == "" {
+		return
+	}
+	posns, err := models.ParseCoords(value)
+	if err != nil || len(posns) != count {
+		f.Errors.Add(field, "This field is invalid")
+		return
+	}
+	for _, posn := range posns {
+		if !posn.On(size) {
+			f.Errors.Add(field, fmt.Sprintf("%s is not on the board", posn))
+		}
+	}
+}
+
 // HorizOrVert checks that a ship is placed horizontally or vertically
 func (f *Form) HorizOrVert(field string) {
-	posns := strings.Split(f.Get(field), ",")
-	nums := make([]int, len(posns))
-	rowNums := make([]int, len(posns))
-	colNums := make([]int, len(posns))
-	for i := range posns {
-		nums[i], _ = strconv.Atoi(strings.TrimSpace(posns[i]))
-	}
-	for i := range rowNums {
-		rowNums[i] = nums[0] + i
+	posns, err := models.ParseCoords(f.Get(field))
+	if err != nil {
+		return
 	}
-	for i := range colNums {
-		colNums[i] = nums[0] + i*10
+	inRow, inCol := true, true
+	for i, posn := range posns {
+		if posn != (models.Coord{Row: posns[0].Row, Col: posns[0].Col + i}) {
+			inRow = false
+		}
+		if posn != (models.Coord{Row: posns[0].Row + i, Col: posns[0].Col}) {
+			inCol = false
+		}
 	}
-	if !sliceEq(nums, rowNums) && !sliceEq(nums, colNums) {
+	if !inRow && !inCol {
 		f.Errors.Add(field, "Ship must be placed horizontally or vertically")
 	}
 }
 
 // NonOverlapping checks that the ship positions do not overlap
 func (f *Form) NonOverlapping(fields ...string) {
-	squareCount := map[string]int{}
+	squareCount := map[models.Coord]int{}
 	for _, field := range fields {
-		posns := strings.Split(f.Get(field), ",")
+		posns, err := models.ParseCoords(f.Get(field))
+		if err != nil {
+			continue
+		}
 		for _, posn := range posns {
 			squareCount[posn]++
 			if squareCount[posn] == 2 {
@@ -130,42 +142,46 @@ func (f *Form) NonOverlapping(fields ...string) {
 }
 
 // ValidateNewGameForm validates the entire form for a new game
-func (f *Form) ValidateNewGameForm() {
-	f.Required("username", "btlship", "cruiser", "frigate", "destroyer", "patrolboat")
+// played by the ruleset
+func (f *Form) ValidateNewGameForm(rules models.Ruleset) {
+	f.Required("username")
+	f.Required(rules.Fields()...)
 	f.MinLength("username", 4)
 	f.MaxLength("username", 10)
-	f.MatchesPattern("btlship", BtlshipRX)
-	f.HorizOrVert("btlship")
-	f.MatchesPattern("cruiser", CruiserRX)
-	f.HorizOrVert("cruiser")
-	f.MatchesPattern("frigate", FrigateRX)
-	f.HorizOrVert("frigate")
-	f.MatchesPattern("destroyer", DestroyerRX)
-	f.HorizOrVert("destroyer")
-	f.MatchesPattern("patrolboat", PatrolboatRX)
-	f.HorizOrVert("patrolboat")
-	f.NonOverlapping("btlship", "cruiser", "frigate", "destroyer", "patrolboat")
+	for _, ship := range rules.Ships {
+		f.Squares(ship.Field, ship.Size, rules.Size)
+		f.HorizOrVert(ship.Field)
+	}
+	f.NonOverlapping(rules.Fields()...)
+}
+
+// userName is the pattern of the names of accounts
+var userName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ValidateSignupForm validates the form that opens an account. The
+// name is also the player's nickname, so it has the same length.
+func (f *Form) ValidateSignupForm() {
+	f.Required("name", "password")
+	f.MinLength("name", 4)
+	f.MaxLength("name", 10)
+	f.MatchesPattern("name", userName)
+	f.MinLength("password", 8)
+	f.MaxLength("password", 72)
+}
+
+// ValidateLoginForm validates the login form
+func (f *Form) ValidateLoginForm() {
+	f.Required("name", "password")
 }
 
 // ValidateFireForm validates the fire position coordinates
-func (f *Form) ValidateFireForm() {
+// on a size x size board
+func (f *Form) ValidateFireForm(size int) {
 	f.Required("target_pos")
-	f.MatchesPattern("target_pos", FirePosRX)
+	f.Squares("target_pos", 1, size)
 }
 
 // Valid validates the form
 func (f *Form) Valid() bool {
 	return len(f.Errors) == 0
 }
-
-func sliceEq(s1, s2 []int) bool {
-	if len(s1) != len(s2) {
-		return false
-	}
-	for i := range s1 {
-		if s1[i] != s2[i] {
-			return false
-		}
-	}
-	return true
-}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxChatLength is the most characters a chat message may have
+const MaxChatLength = 280
+
+// MaxChatLog is the number of chat messages a game keeps
+const MaxChatLog = 100
+
+// Errors returned by Say for a message that is not sent
+var (
+	ErrChatEmpty   = errors.New("models: chat message is empty")
+	ErrChatMuted   = errors.New("models: chat is muted")
+	ErrChatTooLong = errors.New("models: chat message is too long")
+)
+
+// ChatMessage is a message a player sent to the opponent. Text is kept
+// as typed, and escaped by the pages that show it.
+type ChatMessage struct {
+	PlayerID string
+	NickName string
+	Text     string
+	Time     time.Time
+}
+
+// Say adds the player's message to the chat of the game, keeping the
+// last MaxChatLog messages, and returns it. Control characters are
+// taken out and spaces trimmed from both ends before the length is
+// checked. It returns ErrChatMuted if the game's owner muted the chat.
+// The caller must hold g.Mu.
+func (g *Game) Say(playerID, text string) (ChatMessage, error) {
+	pplayer, ok := g.Players[playerID]
+	if !ok || pplayer.Bot {
+		return ChatMessage{}, ErrNoSuchPlayer
+	}
+	if g.ChatMuted {
+		return ChatMessage{}, ErrChatMuted
+	}
+	text = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text))
+	if text == "" {
+		return ChatMessage{}, ErrChatEmpty
+	}
+	if utf8.RuneCountInString(text) > MaxChatLength {
+		return ChatMessage{}, ErrChatTooLong
+	}
+	msg := ChatMessage{
+		PlayerID: playerID,
+		NickName: pplayer.NickName,
+		Text:     text,
+		Time:     time.Now(),
+	}
+	g.Chat = append(g.Chat, msg)
+	g.Active = msg.Time
+	if len(g.Chat) > MaxChatLog {
+		g.Chat = append([]ChatMessage{}, g.Chat[len(g.Chat)-MaxChatLog:]...)
+	}
+	return msg, nil
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+)
+
+// TimeControl is how long the players of a game have to fire. A zero
+// PerShot or PerGame is no limit. A player who runs out of time loses
+// the game, or with AutoFire has a shot fired at random instead.
+type TimeControl struct {
+	Name     string
+	Label    string
+	PerShot  time.Duration // for each shot
+	PerGame  time.Duration // for all the shots of a player, like a chess clock
+	AutoFire bool
+}
+
+// Overtime is how long a player whose game clock has run out has for
+// each further shot, in a game where running out of time fires a shot
+// at random rather than loses it
+const Overtime = 10 * time.Second
+
+// TimeControls are the presets offered on the start form. The first
+// one, no limit, is the default.
+var TimeControls = []TimeControl{
+	{Name: "none", Label: "No time limit"},
+	{Name: "shot30", Label: "30 seconds per shot", PerShot: 30 * time.Second},
+	{Name: "clock5", Label: "5 minutes per player", PerGame: 5 * time.Minute},
+	{Name: "clock10", Label: "10 minutes per player", PerGame: 10 * time.Minute},
+}
+
+// LookupTimeControl returns the preset with the given name, or the
+// default preset if there is no such preset
+func LookupTimeControl(name string) TimeControl {
+	for _, clock := range TimeControls {
+		if clock.Name == name {
+			return clock
+		}
+	}
+	return TimeControls[0]
+}
+
+// Limited tells if the time control limits the time to fire
+func (clock TimeControl) Limited() bool {
+	return clock.PerShot > 0 || clock.PerGame > 0
+}
+
+// startClock starts the turn of the first player, with the game
+// clocks of both players full. The caller must hold g.Mu.
+func (g *Game) startClock(now time.Time) {
+	g.TurnStarted = now
+	if g.Clock.PerGame == 0 {
+		return
+	}
+	g.ClockLeft = map[string]time.Duration{}
+	for id := range g.Players {
+		g.ClockLeft[id] = g.Clock.PerGame
+	}
+}
+
+// chargeClock takes the time the player took for a shot off the
+// player's game clock and starts the next turn. The caller must hold
+// g.Mu.
+func (g *Game) chargeClock(playerID string, now time.Time) {
+	if g.Clock.PerGame > 0 {
+		g.ClockLeft[playerID] = g.GameClock(playerID, now)
+	}
+	g.TurnStarted = now
+}
+
+// GameClock returns the time left on the game clock of the player,
+// which runs during the player's turns. The caller must hold g.Mu.
+func (g *Game) GameClock(playerID string, now time.Time) time.Duration {
+	left := g.ClockLeft[playerID]
+	if g.Status == GamePlaying && g.NextToPlay == playerID {
+		left -= now.Sub(g.TurnStarted)
+	}
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// TimeLeft returns how long the player to play has left to fire, and
+// false if the game is not in progress or not played against the
+// clock. The caller must hold g.Mu.
+func (g *Game) TimeLeft(now time.Time) (time.Duration, bool) {
+	if g.Status != GamePlaying || !g.Clock.Limited() {
+		return 0, false
+	}
+	elapsed := now.Sub(g.TurnStarted)
+	left := g.Clock.PerShot - elapsed
+	if g.Clock.PerGame > 0 {
+		onClock := g.GameClock(g.NextToPlay, now)
+		if g.Clock.AutoFire && g.ClockLeft[g.NextToPlay] == 0 {
+			// the game clock ran out in an earlier turn
+			onClock = Overtime - elapsed
+		}
+		if g.Clock.PerShot == 0 || onClock < left {
+			left = onClock
+		}
+	}
+	if left < 0 {
+		return 0, true
+	}
+	return left, true
+}
+
+// Forfeit ends the game in progress as lost by the player. It returns
+// ErrGameNotActive if the game is not in progress. The caller must
+// hold g.Mu.
+func (g *Game) Forfeit(playerID string) error {
+	if g.Status != GamePlaying {
+		return ErrGameNotActive
+	}
+	pplayer, ok := g.Players[playerID]
+	if !ok {
+		return ErrNoSuchPlayer
+	}
+	g.Status = GameEnded
+	g.Forfeited = playerID
+	g.Active = time.Now()
+	if g.Wins == nil {
+		g.Wins = map[string]int{}
+	}
+	g.Wins[pplayer.OpponentID]++
+	return nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	shot30       = LookupTimeControl("shot30")
+	clock5       = LookupTimeControl("clock5")
+	shotAndClock = TimeControl{Name: "shot30+clock5", PerShot: 30 * time.Second, PerGame: 5 * time.Minute}
+	autoFire     = TimeControl{Name: "clock5", PerGame: 5 * time.Minute, AutoFire: true}
+)
+
+// clockGame starts a duel under the time control at start, with the
+// given time left on the game clock of the first player, who is to
+// play
+func clockGame(t *testing.T, clock TimeControl, onClock time.Duration, start time.Time) (*Game, *Player, *Player) {
+	t.Helper()
+	pgame, pplayer1, pplayer2 := duelGame(t)
+	pgame.Clock = clock
+	pgame.startClock(start)
+	if clock.PerGame > 0 {
+		pgame.ClockLeft[pplayer1.ID] = onClock
+	}
+	return pgame, pplayer1, pplayer2
+}
+
+func TestTimeLeft(t *testing.T) {
+	tests := []struct {
+		name    string
+		clock   TimeControl
+		onClock time.Duration // left on the game clock of the player to play
+		elapsed time.Duration // since the turn started
+		want    time.Duration
+		ok      bool
+	}{
+		{"no limit", TimeControls[0], 0, time.Minute, 0, false},
+		{"per shot", shot30, 0, 10 * time.Second, 20 * time.Second, true},
+		{"per shot, expired", shot30, 0, 40 * time.Second, 0, true},
+		{"per game", clock5, 2 * time.Minute, 30 * time.Second, 90 * time.Second, true},
+		{"per game, expired", clock5, 20 * time.Second, 30 * time.Second, 0, true},
+		{"per game, run out without auto fire", clock5, 0, time.Second, 0, true},
+		{"per shot under a fuller game clock", shotAndClock, 2 * time.Minute, 5 * time.Second, 25 * time.Second, true},
+		{"game clock under the time per shot", shotAndClock, 10 * time.Second, 5 * time.Second, 5 * time.Second, true},
+		{"auto fire, clock running", autoFire, 20 * time.Second, 5 * time.Second, 15 * time.Second, true},
+		{"overtime", autoFire, 0, 4 * time.Second, Overtime - 4*time.Second, true},
+		{"overtime, expired", autoFire, 0, Overtime + time.Second, 0, true},
+	}
+
+	start := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgame, _, _ := clockGame(t, tt.clock, tt.onClock, start)
+			got, ok := pgame.TimeLeft(start.Add(tt.elapsed))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %s, %t; want %s, %t", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTimeLeftNotPlaying(t *testing.T) {
+	start := time.Now()
+	pgame, pplayer1, _ := clockGame(t, shot30, 0, start)
+	if err := pgame.Forfeit(pplayer1.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := pgame.TimeLeft(start); ok {
+		t.Errorf("got %s left in an ended game", got)
+	}
+}
+
+func TestGameClock(t *testing.T) {
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		ended    bool
+		mine     time.Duration // of the player to play
+		opponent time.Duration
+	}{
+		{"turn started", 0, false, 2 * time.Minute, 5 * time.Minute},
+		{"runs for the player to play only", 30 * time.Second, false, 90 * time.Second, 5 * time.Minute},
+		{"run out", 3 * time.Minute, false, 0, 5 * time.Minute},
+		{"stopped once the game ends", 30 * time.Second, true, 2 * time.Minute, 5 * time.Minute},
+	}
+
+	start := time.Now()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgame, pplayer1, pplayer2 := clockGame(t, clock5, 2*time.Minute, start)
+			if tt.ended {
+				pgame.Status = GameEnded
+			}
+			now := start.Add(tt.elapsed)
+			if got := pgame.GameClock(pplayer1.ID, now); got != tt.mine {
+				t.Errorf("got %s on the clock of the player to play; want %s", got, tt.mine)
+			}
+			if got := pgame.GameClock(pplayer2.ID, now); got != tt.opponent {
+				t.Errorf("got %s on the clock of the opponent; want %s", got, tt.opponent)
+			}
+		})
+	}
+}
+
+func TestChargeClock(t *testing.T) {
+	start := time.Now()
+	pgame, pplayer1, _ := clockGame(t, clock5, 2*time.Minute, start)
+	now := start.Add(45 * time.Second)
+	pgame.chargeClock(pplayer1.ID, now)
+	if got := pgame.ClockLeft[pplayer1.ID]; got != 75*time.Second {
+		t.Errorf("got %s left after the shot; want 1m15s", got)
+	}
+	if !pgame.TurnStarted.Equal(now) {
+		t.Errorf("next turn started at %s; want %s", pgame.TurnStarted, now)
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BoardSizes are the board sizes a game can be created with
+var BoardSizes = []int{8, 10, 12, 15}
+
+// ErrBadSquare is returned by ParseCoord for text that is not a square
+var ErrBadSquare = errors.New("models: not a square")
+
+// Coord is a square of a board, counted from 0 at the top left
+type Coord struct {
+	Row int
+	Col int
+}
+
+// String gives the square in the notation of the forms: two digits,
+// row then column, when both are below 10, as in 53, and the row and
+// column separated by a colon otherwise, as in 11:3.
+func (c Coord) String() string {
+	if c.Row < 10 && c.Col < 10 {
+		return fmt.Sprintf("%d%d", c.Row, c.Col)
+	}
+	return fmt.Sprintf("%d:%d", c.Row, c.Col)
+}
+
+// On tells if the square is on a board of the given size
+func (c Coord) On(size int) bool {
+	return c.Row >= 0 && c.Row < size && c.Col >= 0 && c.Col < size
+}
+
+// ParseCoord reads a square written as String writes it.
+// Surrounding spaces are ignored.
+func ParseCoord(s string) (Coord, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ":"); i >= 0 {
+		row, err := strconv.Atoi(strings.TrimSpace(s[:i]))
+		if err != nil {
+			return Coord{}, ErrBadSquare
+		}
+		col, err := strconv.Atoi(strings.TrimSpace(s[i+1:]))
+		if err != nil || row < 0 || col < 0 {
+			return Coord{}, ErrBadSquare
+		}
+		return Coord{row, col}, nil
+	}
+	if len(s) != 2 || s[0] < '0' || s[0] > '9' || s[1] < '0' || s[1] > '9' {
+		return Coord{}, ErrBadSquare
+	}
+	return Coord{int(s[0] - '0'), int(s[1] - '0')}, nil
+}
+
+// ParseCoords reads a comma separated list of squares
+func ParseCoords(s string) ([]Coord, error) {
+	fields := strings.Split(s, ",")
+	coords := make([]Coord, len(fields))
+	for i, field := range fields {
+		c, err := ParseCoord(field)
+		if err != nil {
+			return nil, err
+		}
+		coords[i] = c
+	}
+	return coords, nil
+}
+
+// ValidSize tells if a board size is one of BoardSizes
+func ValidSize(size int) bool {
+	for _, s := range BoardSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
+// newBoard makes an empty size x size board
+func newBoard(size int) [][]string {
+	board := make([][]string, size)
+	for i := range board {
+		board[i] = make([]string, size)
+	}
+	return board
+}
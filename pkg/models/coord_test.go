@@ -0,0 +1,75 @@
+package models
+
+import "testing"
+
+func TestParseCoord(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Coord
+		err  error
+	}{
+		{"53", Coord{5, 3}, nil},
+		{"00", Coord{0, 0}, nil},
+		{" 99 ", Coord{9, 9}, nil},
+		{"11:3", Coord{11, 3}, nil},
+		{"3:14", Coord{3, 14}, nil},
+		{"14:14", Coord{14, 14}, nil},
+		{" 12 : 0 ", Coord{12, 0}, nil},
+		{"0:5", Coord{0, 5}, nil},
+		{"", Coord{}, ErrBadSquare},
+		{"5", Coord{}, ErrBadSquare},
+		{"123", Coord{}, ErrBadSquare},
+		{"a3", Coord{}, ErrBadSquare},
+		{"3b", Coord{}, ErrBadSquare},
+		{"-1", Coord{}, ErrBadSquare},
+		{"11:", Coord{}, ErrBadSquare},
+		{":3", Coord{}, ErrBadSquare},
+		{"-1:3", Coord{}, ErrBadSquare},
+		{"3:-1", Coord{}, ErrBadSquare},
+		{"1:2:3", Coord{}, ErrBadSquare},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCoord(tt.in)
+		if err != tt.err {
+			t.Errorf("ParseCoord(%q): got error %v; want %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCoord(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCoordStringRoundTrip(t *testing.T) {
+	for _, size := range BoardSizes {
+		for row := 0; row < size; row++ {
+			for col := 0; col < size; col++ {
+				c := Coord{row, col}
+				got, err := ParseCoord(c.String())
+				if err != nil || got != c {
+					t.Fatalf("ParseCoord(%q) = %v, %v; want %v", c.String(), got, err, c)
+				}
+			}
+		}
+	}
+}
+
+func TestParseCoords(t *testing.T) {
+	got, err := ParseCoords("11:3,11:4,11:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Coord{{11, 3}, {11, 4}, {11, 5}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v; want %v", got, want)
+		}
+	}
+	if _, err := ParseCoords("23,2x,25"); err != ErrBadSquare {
+		t.Errorf("got %v; want ErrBadSquare", err)
+	}
+}
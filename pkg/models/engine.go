@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Game status values
+const (
+	GameStarting = iota
+	GamePlaying
+	GameEnded
+)
+
+// ShotOutcome tells what a shot did to the opponent's fleet
+type ShotOutcome int
+
+// The possible outcomes of a shot
+const (
+	Miss ShotOutcome = iota
+	Hit
+	Sunk
+)
+
+// ShotResult is the typed result of firing at a square.
+// Class and Wreck, the squares of the ship, are set only
+// when a ship has been sunk.
+type ShotResult struct {
+	Pos      Coord
+	Outcome  ShotOutcome
+	Class    string
+	Wreck    []Coord
+	GameOver bool
+}
+
+// String gives the outcome as used in the move log
+func (o ShotOutcome) String() string {
+	switch o {
+	case Hit:
+		return "hit"
+	case Sunk:
+		return "sunk"
+	}
+	return "miss"
+}
+
+// Square gives the fired at square in the notation of the forms
+func (r ShotResult) Square() string {
+	return r.Pos.String()
+}
+
+// Shot is an entry in a player's shot history. Turn counts the shots
+// fired in the game by both players, starting from 1.
+type Shot struct {
+	ShotResult
+	Turn int
+	Time time.Time
+}
+
+// Move is a shot together with the nickname of the player who fired it
+type Move struct {
+	NickName string
+	Shot
+}
+
+// Errors returned by Fire
+var (
+	ErrGameNotActive = errors.New("models: game is not in progress")
+	ErrNoSuchPlayer  = errors.New("models: no such player in game")
+	ErrNotYourTurn   = errors.New("models: not this player's turn")
+	ErrOffBoard      = errors.New("models: square is not on the board")
+	ErrRepeatedShot  = errors.New("models: square has already been fired at")
+)
+
+// Fire fires a shot by player playerID at square pos of the opponent's
+// board. It enforces turn order, rejects shots at squares already fired
+// at, updates both boards and the fleets, and ends the game or, unless
+// the ruleset gives the player another shot, passes the turn to the
+// opponent. The caller must hold g.Mu.
+func (g *Game) Fire(playerID string, pos Coord) (ShotResult, error) {
+	result := ShotResult{Pos: pos}
+	if g.Status != GamePlaying {
+		return result, ErrGameNotActive
+	}
+	pplayer, ok := g.Players[playerID]
+	if !ok {
+		return result, ErrNoSuchPlayer
+	}
+	if g.NextToPlay != playerID {
+		return result, ErrNotYourTurn
+	}
+	if !pos.On(g.Rules.Size) {
+		return result, ErrOffBoard
+	}
+	if pplayer.ShotsBoard[pos.Row][pos.Col] != "" {
+		return result, ErrRepeatedShot
+	}
+	popponent, ok := g.Players[pplayer.OpponentID]
+	if !ok {
+		return result, ErrNoSuchPlayer
+	}
+
+outer:
+	for i, pship := range popponent.Ships {
+		for partIndex, shipPart := range pship.Parts {
+			if pos == shipPart.Pos {
+				result.Outcome = Hit
+				delete(pship.Parts, partIndex)
+				if len(pship.Parts) == 0 {
+					delete(popponent.Ships, i)
+					result.Outcome = Sunk
+					result.Class = pship.Class
+					result.Wreck = pship.Squares
+				}
+				break outer
+			}
+		}
+	}
+
+	if result.Outcome == Miss {
+		pplayer.ShotsBoard[pos.Row][pos.Col] = "splash"
+	} else {
+		popponent.Board[pos.Row][pos.Col] = popponent.Board[pos.Row][pos.Col] + "_fire"
+		pplayer.ShotsBoard[pos.Row][pos.Col] = "hit_bomb"
+	}
+
+	now := time.Now()
+	g.Active = now
+	g.Turns++
+	pplayer.Shots = append(pplayer.Shots, Shot{ShotResult: result, Turn: g.Turns, Time: now})
+	g.chargeClock(playerID, now)
+
+	switch {
+	case len(popponent.Ships) == 0:
+		result.GameOver = true
+		pplayer.Shots[len(pplayer.Shots)-1].GameOver = true
+		g.Status = GameEnded
+		if g.Wins == nil {
+			g.Wins = map[string]int{}
+		}
+		g.Wins[playerID]++
+	case result.Outcome != Miss && g.Rules.ExtraShotOnHit:
+		// the player fires again
+	default:
+		g.NextToPlay = popponent.ID
+	}
+	return result, nil
+}
+
+// Moves returns the shots of both players ordered by turn.
+// The caller must hold g.Mu.
+func (g *Game) Moves() []Move {
+	moves := []Move{}
+	for _, pplayer := range g.Players {
+		for _, shot := range pplayer.Shots {
+			moves = append(moves, Move{NickName: pplayer.NickName, Shot: shot})
+		}
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].Turn < moves[j].Turn })
+	return moves
+}
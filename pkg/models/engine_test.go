@@ -0,0 +1,193 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+// duel is a ruleset with a single patrol boat, so that two hits win
+var duel = Ruleset{
+	Name:  "duel",
+	Size:  8,
+	Ships: []ShipClass{{"patrolboat", "patrolboat", 2}},
+}
+
+// newTestGame starts a game of the ruleset between two players with
+// the given fleets. The first player fires first.
+func newTestGame(t *testing.T, rules Ruleset, fleet1, fleet2 url.Values) (*Game, *Player, *Player) {
+	t.Helper()
+	fleet1.Set("username", "alice")
+	pgame, err := NewGame(fleet1, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fleet2.Set("username", "bobby")
+	pplayer2, err := NewPlayer(fleet2, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pgame.Join(pplayer2)
+	return pgame, pgame.Players[pgame.Owner], pplayer2
+}
+
+func duelGame(t *testing.T) (*Game, *Player, *Player) {
+	return newTestGame(t, duel,
+		url.Values{"patrolboat": {"00,01"}},
+		url.Values{"patrolboat": {"55,65"}})
+}
+
+func TestFireErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(g *Game, p1, p2 *Player)
+		player func(p1, p2 *Player) string
+		pos    Coord
+		want   error
+	}{
+		{
+			name:   "game not started",
+			setup:  func(g *Game, p1, p2 *Player) { g.Status = GameStarting },
+			player: func(p1, p2 *Player) string { return p1.ID },
+			want:   ErrGameNotActive,
+		},
+		{
+			name:   "game ended",
+			setup:  func(g *Game, p1, p2 *Player) { g.Status = GameEnded },
+			player: func(p1, p2 *Player) string { return p1.ID },
+			want:   ErrGameNotActive,
+		},
+		{
+			name:   "no such player",
+			player: func(p1, p2 *Player) string { return "nobody" },
+			want:   ErrNoSuchPlayer,
+		},
+		{
+			name:   "not your turn",
+			player: func(p1, p2 *Player) string { return p2.ID },
+			want:   ErrNotYourTurn,
+		},
+		{
+			name:   "off the board",
+			player: func(p1, p2 *Player) string { return p1.ID },
+			pos:    Coord{8, 0},
+			want:   ErrOffBoard,
+		},
+		{
+			name:   "negative square",
+			player: func(p1, p2 *Player) string { return p1.ID },
+			pos:    Coord{0, -1},
+			want:   ErrOffBoard,
+		},
+		{
+			name:   "repeated shot",
+			setup:  func(g *Game, p1, p2 *Player) { p1.ShotsBoard[3][3] = "splash" },
+			player: func(p1, p2 *Player) string { return p1.ID },
+			pos:    Coord{3, 3},
+			want:   ErrRepeatedShot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgame, p1, p2 := duelGame(t)
+			if tt.setup != nil {
+				tt.setup(pgame, p1, p2)
+			}
+			_, err := pgame.Fire(tt.player(p1, p2), tt.pos)
+			if err != tt.want {
+				t.Errorf("got error %v; want %v", err, tt.want)
+			}
+			if pgame.Turns != 0 {
+				t.Errorf("a refused shot counted as turn %d", pgame.Turns)
+			}
+		})
+	}
+}
+
+func TestFireOutcomes(t *testing.T) {
+	pgame, p1, p2 := duelGame(t)
+
+	steps := []struct {
+		player   *Player
+		pos      Coord
+		outcome  ShotOutcome
+		gameOver bool
+		next     *Player
+	}{
+		{p1, Coord{0, 0}, Miss, false, p2},
+		{p2, Coord{0, 0}, Hit, false, p1},
+		{p1, Coord{5, 5}, Hit, false, p2},
+		{p2, Coord{7, 7}, Miss, false, p1},
+		{p1, Coord{6, 5}, Sunk, true, p1},
+	}
+	for i, step := range steps {
+		result, err := pgame.Fire(step.player.ID, step.pos)
+		if err != nil {
+			t.Fatalf("shot %d: %v", i+1, err)
+		}
+		if result.Outcome != step.outcome {
+			t.Errorf("shot %d: got %s; want %s", i+1, result.Outcome, step.outcome)
+		}
+		if result.GameOver != step.gameOver {
+			t.Errorf("shot %d: got GameOver %t; want %t", i+1, result.GameOver, step.gameOver)
+		}
+		if pgame.NextToPlay != step.next.ID {
+			t.Errorf("shot %d: %s to play; want %s", i+1, pgame.Players[pgame.NextToPlay].NickName, step.next.NickName)
+		}
+		if pgame.Turns != i+1 {
+			t.Errorf("shot %d: Turns is %d", i+1, pgame.Turns)
+		}
+	}
+
+	last := p1.Shots[len(p1.Shots)-1]
+	if last.Class != "patrolboat" || len(last.Wreck) != 2 || !last.GameOver {
+		t.Errorf("got last shot %+v; want the wreck of the patrol boat", last)
+	}
+	if pgame.Status != GameEnded {
+		t.Errorf("got status %d; want GameEnded", pgame.Status)
+	}
+	if pgame.Wins[p1.ID] != 1 || pgame.Wins[p2.ID] != 0 {
+		t.Errorf("got wins %v; want 1 for alice", pgame.Wins)
+	}
+	if p1.Board[0][0] != "end_left_fire" {
+		t.Errorf("got %q on alice's hit square", p1.Board[0][0])
+	}
+	if _, err := pgame.Fire(p1.ID, Coord{1, 1}); err != ErrGameNotActive {
+		t.Errorf("fired after the end: got %v; want ErrGameNotActive", err)
+	}
+}
+
+func TestFireExtraShotOnHit(t *testing.T) {
+	rules := duel
+	rules.ExtraShotOnHit = true
+	pgame, p1, p2 := newTestGame(t, rules,
+		url.Values{"patrolboat": {"00,01"}},
+		url.Values{"patrolboat": {"55,56"}})
+
+	steps := []struct {
+		player  *Player
+		pos     Coord
+		outcome ShotOutcome
+		next    *Player
+	}{
+		{p1, Coord{5, 5}, Hit, p1},  // a hit fires again
+		{p1, Coord{4, 4}, Miss, p2}, // a miss passes the turn
+		{p2, Coord{0, 0}, Hit, p2},
+		{p2, Coord{0, 1}, Sunk, p2}, // the last ship ends the game
+	}
+	for i, step := range steps {
+		result, err := pgame.Fire(step.player.ID, step.pos)
+		if err != nil {
+			t.Fatalf("shot %d: %v", i+1, err)
+		}
+		if result.Outcome != step.outcome {
+			t.Errorf("shot %d: got %s; want %s", i+1, result.Outcome, step.outcome)
+		}
+		if pgame.NextToPlay != step.next.ID {
+			t.Errorf("shot %d: %s to play; want %s", i+1, pgame.Players[pgame.NextToPlay].NickName, step.next.NickName)
+		}
+	}
+	if pgame.Status != GameEnded || pgame.Wins[p2.ID] != 1 {
+		t.Errorf("got status %d and wins %v; want bobby to win", pgame.Status, pgame.Wins)
+	}
+}
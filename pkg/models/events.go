@@ -0,0 +1,194 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType tells what an Event is about
+type EventType string
+
+// The types of the events of a game
+const (
+	EventJoined   EventType = "joined"   // the second player joined
+	EventShot     EventType = "shot"     // a shot was fired
+	EventSunk     EventType = "sunk"     // the shot sank a ship
+	EventGameOver EventType = "gameover" // the shot sank the last ship
+	EventChat     EventType = "chat"     // a player sent a chat message
+	EventForfeit  EventType = "forfeit"  // a player forfeited the game
+
+	EventSpectators EventType = "spectators" // the number of spectators changed
+	EventRematch    EventType = "rematch"    // a player asked for a rematch, or it was made
+	EventLeft       EventType = "left"       // a player left the game after it ended
+	EventMuted      EventType = "muted"      // the owner muted or unmuted the chat
+	EventAway       EventType = "away"       // a player closed the page of the game in progress
+	EventBack       EventType = "back"       // a player who had gone opened the page again
+	EventExpired    EventType = "expired"    // the game was idle too long and is being removed
+
+	// on the bus of the lobby rather than of a game
+	EventLobby EventType = "lobby" // a game was opened, filled, made private or public, or removed
+
+	// on the bus of a player waiting in the matchmaking queue
+	EventMatched EventType = "matched" // the player was paired and the game made
+)
+
+// Event is something that happened in a game
+type Event struct {
+	Seq      int // numbers the events of a game from 1
+	Type     EventType
+	GameID   string // for EventLobby and EventMatched
+	PlayerID string // the player who joined, fired, chatted, forfeited, went away or came back, asked for a rematch or left
+	NickName string
+	Shot     Shot   // for EventShot, EventSunk and EventGameOver
+	Text     string // for EventChat, and for EventForfeit why the player forfeited
+	Count    int    // for EventSpectators
+	Time     time.Time
+}
+
+// EventHistory is the number of past events a Bus keeps
+// for subscribers that resume after a disconnection
+const EventHistory = 256
+
+// subscriberBuffer is the number of events a subscriber may
+// fall behind before it is dropped
+const subscriberBuffer = 16
+
+// Bus fans the events of a game out to its subscribers. Publish never
+// waits for a subscriber: one that has fallen too far behind has its
+// channel closed and may subscribe again, resuming after the last
+// event it got. A Bus has its own lock and may be used without holding
+// the game's Mu.
+type Bus struct {
+	mu         sync.Mutex
+	seq        int
+	history    []Event
+	subs       map[chan Event]bool // true for the channels of spectators
+	spectators int
+}
+
+// NewBus makes a bus with no events and no subscribers
+func NewBus() *Bus {
+	return &Bus{subs: map[chan Event]bool{}}
+}
+
+// Seq returns the number of the last event published
+func (b *Bus) Seq() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// Spectators returns the number of spectators subscribed
+func (b *Bus) Spectators() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spectators
+}
+
+// Publish numbers the event, keeps it in the history and sends
+// it to the subscribers
+func (b *Bus) Publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publish(ev)
+}
+
+// publish is Publish with b.mu held
+func (b *Bus) publish(ev Event) {
+	b.seq++
+	ev.Seq = b.seq
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	b.history = append(b.history, ev)
+	if len(b.history) > EventHistory {
+		b.history = b.history[len(b.history)-EventHistory:]
+	}
+	// drop after sending, as dropping a spectator publishes
+	// an event that must not overtake this one
+	full := []chan Event{}
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			full = append(full, ch)
+		}
+	}
+	for _, ch := range full {
+		b.drop(ch)
+	}
+}
+
+// drop closes a channel if it is still subscribed. Dropping a
+// spectator publishes the new count. b.mu must be held.
+func (b *Bus) drop(ch chan Event) {
+	spectator, ok := b.subs[ch]
+	if !ok {
+		return
+	}
+	delete(b.subs, ch)
+	close(ch)
+	if spectator {
+		b.spectators--
+		b.publish(Event{Type: EventSpectators, Count: b.spectators})
+	}
+}
+
+// Subscribe returns the events published after event number after
+// that are still in the history, and a channel receiving the events
+// published from now on. ok is false if events after after are missing
+// from the history, or if after is ahead of the bus, as it is for a
+// subscriber from before a restart of the server; the subscriber then
+// has to start from the state of the game instead.
+func (b *Bus) Subscribe(after int) (missed []Event, ch chan Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribe(after, false)
+}
+
+// Watch is Subscribe for a spectator. The spectator is counted until
+// unsubscribed, and the new count is published as an EventSpectators
+// event, which the spectator gets too.
+func (b *Bus) Watch(after int) (missed []Event, ch chan Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	missed, ch, ok = b.subscribe(after, true)
+	b.spectators++
+	b.publish(Event{Type: EventSpectators, Count: b.spectators})
+	return missed, ch, ok
+}
+
+// subscribe is Subscribe with b.mu held
+func (b *Bus) subscribe(after int, spectator bool) (missed []Event, ch chan Event, ok bool) {
+	ok = after <= b.seq
+	for _, ev := range b.history {
+		if ev.Seq > after {
+			missed = append(missed, ev)
+		}
+	}
+	if ok && after < b.seq && (len(missed) == 0 || missed[0].Seq != after+1) {
+		ok = false
+	}
+	ch = make(chan Event, subscriberBuffer)
+	b.subs[ch] = spectator
+	return missed, ch, ok
+}
+
+// Unsubscribe stops sending events to ch. ch may already have
+// been dropped by Publish.
+func (b *Bus) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(ch)
+}
+
+// DropSpectators closes the channels of all the spectators
+func (b *Bus) DropSpectators() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, spectator := range b.subs {
+		if spectator {
+			b.drop(ch)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package models
+
+import "testing"
+
+// publishN publishes n chat events on the bus
+func publishN(b *Bus, n int) {
+	for i := 0; i < n; i++ {
+		b.Publish(Event{Type: EventChat})
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	tests := []struct {
+		name      string
+		published int
+		after     int
+		ok        bool
+		first     int // Seq of the first missed event, 0 for none
+		missed    int
+	}{
+		{"from the start", 10, 0, true, 1, 10},
+		{"from the middle", 10, 4, true, 5, 6},
+		{"up to date", 10, 10, true, 0, 0},
+		{"ahead of the bus", 10, 12, false, 0, 0},
+		{"middle of a full history", EventHistory + 50, 100, true, 101, EventHistory + 50 - 100},
+		{"start of a full history", EventHistory + 50, 50, true, 51, EventHistory},
+		{"before the history", EventHistory + 50, 49, false, 51, EventHistory},
+		{"from scratch after the history", EventHistory + 50, 0, false, 51, EventHistory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBus()
+			publishN(b, tt.published)
+			missed, ch, ok := b.Subscribe(tt.after)
+			defer b.Unsubscribe(ch)
+			if ok != tt.ok {
+				t.Errorf("got ok %t; want %t", ok, tt.ok)
+			}
+			if len(missed) != tt.missed {
+				t.Fatalf("got %d missed events; want %d", len(missed), tt.missed)
+			}
+			for i, ev := range missed {
+				if ev.Seq != tt.first+i {
+					t.Fatalf("missed event %d is number %d; want %d", i, ev.Seq, tt.first+i)
+				}
+			}
+
+			b.Publish(Event{Type: EventShot})
+			ev := <-ch
+			if ev.Seq != tt.published+1 || ev.Type != EventShot {
+				t.Errorf("got event %d %s; want %d shot", ev.Seq, ev.Type, tt.published+1)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	b := NewBus()
+	_, slow, _ := b.Subscribe(0)
+	_, fast, _ := b.Subscribe(0)
+
+	// fill the slow subscriber's buffer while the fast one reads
+	for i := 0; i < subscriberBuffer; i++ {
+		b.Publish(Event{Type: EventChat})
+		<-fast
+	}
+	// one more than the buffer holds drops the slow subscriber
+	b.Publish(Event{Type: EventChat})
+	if ev := <-fast; ev.Seq != subscriberBuffer+1 {
+		t.Errorf("fast subscriber got event %d; want %d", ev.Seq, subscriberBuffer+1)
+	}
+
+	last := 0
+	for ev := range slow {
+		last = ev.Seq
+	}
+	if last != subscriberBuffer {
+		t.Errorf("slow subscriber got up to event %d; want %d", last, subscriberBuffer)
+	}
+
+	// the dropped subscriber resumes after the last event it got
+	missed, ch, ok := b.Subscribe(last)
+	defer b.Unsubscribe(ch)
+	if !ok || len(missed) != 1 || missed[0].Seq != subscriberBuffer+1 {
+		t.Errorf("resumed with %v, ok %t; want event %d", missed, ok, subscriberBuffer+1)
+	}
+	// unsubscribing a dropped channel does nothing
+	b.Unsubscribe(slow)
+	b.Unsubscribe(fast)
+}
+
+func TestWatchCountsSpectators(t *testing.T) {
+	b := NewBus()
+	_, player, _ := b.Subscribe(0)
+	_, spectator, _ := b.Watch(0)
+	if n := b.Spectators(); n != 1 {
+		t.Fatalf("got %d spectators; want 1", n)
+	}
+	for _, ch := range []chan Event{player, spectator} {
+		if ev := <-ch; ev.Type != EventSpectators || ev.Count != 1 {
+			t.Errorf("got %s event, count %d; want spectators, 1", ev.Type, ev.Count)
+		}
+	}
+
+	// a slow spectator dropped is no longer counted
+	for i := 0; i < subscriberBuffer; i++ {
+		b.Publish(Event{Type: EventChat})
+		<-player
+	}
+	b.Publish(Event{Type: EventChat})
+	<-player
+	if n := b.Spectators(); n != 0 {
+		t.Errorf("got %d spectators after the drop; want 0", n)
+	}
+	if ev := <-player; ev.Type != EventSpectators || ev.Count != 0 {
+		t.Errorf("got %s event, count %d; want spectators, 0", ev.Type, ev.Count)
+	}
+	b.Unsubscribe(player)
+}
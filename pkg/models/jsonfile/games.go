@@ -0,0 +1,135 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjpgt/battleship/pkg/models"
+	"github.com/rjpgt/battleship/pkg/models/memory"
+)
+
+// GameModel keeps games in memory and writes every change to a
+// game to its own JSON file in Dir, so games survive a restart.
+type GameModel struct {
+	*memory.GameModel
+	Dir string
+}
+
+// Open creates dir if needed and loads the games saved in it
+func Open(dir string) (*GameModel, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	m := &GameModel{GameModel: memory.New(), Dir: dir}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		pgame := &models.Game{}
+		err = json.Unmarshal(data, pgame)
+		if err != nil {
+			return nil, err
+		}
+		pgame.Restore()
+		m.GameModel.Put(pgame)
+	}
+	return m, nil
+}
+
+// Put adds a game and saves it
+func (m *GameModel) Put(pgame *models.Game) error {
+	pgame.Mu.Lock()
+	defer pgame.Mu.Unlock()
+	err := m.save(pgame)
+	if err != nil {
+		return err
+	}
+	return m.GameModel.Put(pgame)
+}
+
+// Delete removes a game and its file. It holds the game's Mu, so that
+// an Update in progress saves the game before the file is removed.
+func (m *GameModel) Delete(id string) error {
+	if pgame, ok := m.GameModel.Get(id); ok {
+		pgame.Mu.Lock()
+		defer pgame.Mu.Unlock()
+		m.GameModel.Forget(pgame)
+	}
+	err := os.Remove(m.path(id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteIf calls fn with the game's Mu held, and removes the game and
+// its file before releasing it if fn returns true. The game is kept
+// if its file cannot be removed.
+func (m *GameModel) DeleteIf(id string, fn func(*models.Game) bool) error {
+	return m.GameModel.Update(id, func(pgame *models.Game) error {
+		if !fn(pgame) {
+			return nil
+		}
+		err := os.Remove(m.path(id))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		m.GameModel.Forget(pgame)
+		return nil
+	})
+}
+
+// View calls fn with the game's Mu held without saving the game
+func (m *GameModel) View(id string, fn func(*models.Game) error) error {
+	return m.GameModel.Update(id, fn)
+}
+
+// Update calls fn with the game's Mu held and saves the
+// game if fn returns nil. If fn returns an error, the changes
+// it made stay in memory but are not saved.
+func (m *GameModel) Update(id string, fn func(*models.Game) error) error {
+	return m.GameModel.Update(id, func(pgame *models.Game) error {
+		err := fn(pgame)
+		if err != nil {
+			return err
+		}
+		return m.save(pgame)
+	})
+}
+
+func (m *GameModel) path(id string) string {
+	return filepath.Join(m.Dir, id+".json")
+}
+
+// save writes the game to a temporary file first and renames it,
+// so a crash never leaves a half written game behind. A deleted
+// game is not saved. The file is readable by its owner only, as
+// it holds the tokens of the players. The caller must hold pgame.Mu.
+func (m *GameModel) save(pgame *models.Game) error {
+	if strings.ContainsAny(pgame.ID, `/\.`) {
+		return models.ErrNoGame
+	}
+	if pgame.Deleted {
+		return nil
+	}
+	data, err := json.Marshal(pgame)
+	if err != nil {
+		return err
+	}
+	tmp := m.path(pgame.ID) + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path(pgame.ID))
+}
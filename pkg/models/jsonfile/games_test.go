@@ -0,0 +1,188 @@
+package jsonfile
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// openTemp opens a store in a temporary directory, which the
+// caller removes
+func openTemp(t *testing.T) (*GameModel, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Open(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return m, dir
+}
+
+func newGame(t *testing.T) *models.Game {
+	t.Helper()
+	rules := models.Rulesets[0]
+	form, err := models.RandomFleet(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form.Set("username", "alice")
+	pgame, err := models.NewGame(form, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pgame
+}
+
+// reopened tells if the game is in the store when dir is opened again
+func reopened(t *testing.T, dir, id string) bool {
+	t.Helper()
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := m.Get(id)
+	return ok
+}
+
+func TestDeleteWaitsForUpdate(t *testing.T) {
+	m, dir := openTemp(t)
+	defer os.RemoveAll(dir)
+	pgame := newGame(t)
+	if err := m.Put(pgame); err != nil {
+		t.Fatal(err)
+	}
+
+	inside := make(chan bool)
+	release := make(chan bool)
+	updated := make(chan error)
+	go func() {
+		updated <- m.Update(pgame.ID, func(pgame *models.Game) error {
+			inside <- true
+			<-release
+			pgame.Private = true
+			return nil
+		})
+	}()
+	<-inside
+
+	deleted := make(chan error)
+	go func() {
+		deleted <- m.Delete(pgame.ID)
+	}()
+	select {
+	case <-deleted:
+		t.Fatal("Delete did not wait for the Update in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-updated; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-deleted; err != nil {
+		t.Fatal(err)
+	}
+
+	if reopened(t, dir, pgame.ID) {
+		t.Error("the deleted game came back after a restart")
+	}
+}
+
+func TestUpdateAfterDelete(t *testing.T) {
+	m, dir := openTemp(t)
+	defer os.RemoveAll(dir)
+	pgame := newGame(t)
+	if err := m.Put(pgame); err != nil {
+		t.Fatal(err)
+	}
+
+	// an Update that got the game before it was deleted, and
+	// waited for its Mu
+	pgame.Mu.Lock()
+	updated := make(chan error)
+	ran := false
+	go func() {
+		updated <- m.Update(pgame.ID, func(pgame *models.Game) error {
+			ran = true
+			return nil
+		})
+	}()
+	time.Sleep(50 * time.Millisecond)
+	m.GameModel.Forget(pgame)
+	os.Remove(m.path(pgame.ID))
+	pgame.Mu.Unlock()
+
+	if err := <-updated; err != models.ErrNoGame {
+		t.Errorf("got %v; want ErrNoGame", err)
+	}
+	if ran {
+		t.Error("Update ran on a deleted game")
+	}
+	if err := m.save(pgame); err != nil {
+		t.Fatal(err)
+	}
+	if reopened(t, dir, pgame.ID) {
+		t.Error("a deleted game was saved")
+	}
+}
+
+func TestSaveOwnerOnly(t *testing.T) {
+	m, dir := openTemp(t)
+	defer os.RemoveAll(dir)
+	pgame := newGame(t)
+	if err := m.Put(pgame); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(m.path(pgame.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("game file has mode %o; want 600", perm)
+	}
+}
+
+func TestDeleteIf(t *testing.T) {
+	m, dir := openTemp(t)
+	defer os.RemoveAll(dir)
+	pgame := newGame(t)
+	if err := m.Put(pgame); err != nil {
+		t.Fatal(err)
+	}
+
+	err := m.DeleteIf(pgame.ID, func(pgame *models.Game) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get(pgame.ID); !ok || !reopened(t, dir, pgame.ID) {
+		t.Fatal("a game kept by fn was removed")
+	}
+
+	// an Update that waits for the Mu while fn decides finds the
+	// game gone, rather than changing a game removed after it
+	updated := make(chan error)
+	err = m.DeleteIf(pgame.ID, func(pgame *models.Game) bool {
+		go func() {
+			updated <- m.Update(pgame.ID, func(pgame *models.Game) error {
+				return nil
+			})
+		}()
+		time.Sleep(50 * time.Millisecond)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-updated; err != models.ErrNoGame {
+		t.Errorf("got %v; want ErrNoGame", err)
+	}
+	if _, ok := m.Get(pgame.ID); ok || reopened(t, dir, pgame.ID) {
+		t.Error("a game removed by fn is still kept")
+	}
+}
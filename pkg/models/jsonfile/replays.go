@@ -0,0 +1,68 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// ReplayModel keeps every replay in its own JSON file in Dir.
+// Replays are read from their file when asked for.
+type ReplayModel struct {
+	Dir string
+}
+
+// OpenReplays creates dir if needed
+func OpenReplays(dir string) (*ReplayModel, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayModel{Dir: dir}, nil
+}
+
+// Get reads the replay with the given ID
+func (m *ReplayModel) Get(id string) (*models.Replay, error) {
+	if strings.ContainsAny(id, `/\.`) {
+		return nil, models.ErrNoReplay
+	}
+	data, err := ioutil.ReadFile(m.path(id))
+	if os.IsNotExist(err) {
+		return nil, models.ErrNoReplay
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &models.Replay{}
+	err = json.Unmarshal(data, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Put saves a replay, replacing the replay with the same ID.
+// Like games, it is written to a temporary file first.
+func (m *ReplayModel) Put(r *models.Replay) error {
+	if strings.ContainsAny(r.ID, `/\.`) {
+		return models.ErrNoReplay
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	tmp := m.path(r.ID) + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path(r.ID))
+}
+
+func (m *ReplayModel) path(id string) string {
+	return filepath.Join(m.Dir, id+".json")
+}
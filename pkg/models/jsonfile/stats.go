@@ -0,0 +1,103 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rjpgt/battleship/pkg/models"
+	"github.com/rjpgt/battleship/pkg/models/memory"
+)
+
+// StatsModel keeps statistics in memory and writes all of them, with
+// the IDs of the games whose results are recorded, to the JSON file
+// File whenever a result is recorded.
+type StatsModel struct {
+	*memory.StatsModel
+	File string
+	mu   sync.Mutex // serializes Record
+}
+
+// statsFile is the content of the file of a StatsModel
+type statsFile struct {
+	Stats    []*models.Stats
+	Recorded []string // game IDs
+}
+
+// OpenStats creates the directory of file if needed and loads the
+// statistics saved in file, if it exists. As with OpenUsers, the
+// directory must not be the one of a GameModel.
+func OpenStats(file string) (*StatsModel, error) {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return nil, err
+	}
+	m := &StatsModel{StatsModel: memory.NewStats(), File: file}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	saved := statsFile{}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		// files written before game IDs were kept hold the stats only
+		err = json.Unmarshal(data, &saved.Stats)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.StatsModel.Put(saved.Stats, saved.Recorded...)
+	return m, nil
+}
+
+// Record applies a result to the statistics of its players, unless
+// the result of the game is already recorded. The statistics are
+// saved before they are kept in memory, so a result that cannot be
+// saved is not recorded at all.
+func (m *StatsModel) Record(r *models.Result) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	updated, ok := m.StatsModel.Apply(r)
+	if !ok {
+		return nil
+	}
+	saved := statsFile{
+		Stats:    updated,
+		Recorded: append(m.StatsModel.Recorded(), r.GameID),
+	}
+	for _, s := range m.StatsModel.List() {
+		isUpdated := false
+		for _, u := range updated {
+			isUpdated = isUpdated || u.UserID == s.UserID
+		}
+		if !isUpdated {
+			saved.Stats = append(saved.Stats, s)
+		}
+	}
+	err := m.save(saved)
+	if err != nil {
+		return err
+	}
+	m.StatsModel.Put(updated, r.GameID)
+	return nil
+}
+
+// save writes the statistics to a temporary file first and renames
+// it, like games. The caller must hold m.mu.
+func (m *StatsModel) save(saved statsFile) error {
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	tmp := m.File + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.File)
+}
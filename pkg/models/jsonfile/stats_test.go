@@ -0,0 +1,70 @@
+package jsonfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+func TestRecordOnceAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "stats", "stats.json")
+	m, err := OpenStats(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &models.Result{
+		GameID: "g1",
+		Players: [2]models.PlayerResult{
+			{UserID: "u1", Name: "alice", Shots: 20, Hits: 17},
+			{UserID: "u2", Name: "bobby", Shots: 19, Hits: 10},
+		},
+		Ranked: true,
+	}
+	// the same result recorded from several places at once
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Record(r); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	m, err = OpenStats(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a retry after the restart is ignored too
+	if err := m.Record(r); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		userID       string
+		wins, losses int
+		rating       int
+	}{
+		{"u1", 1, 0, 1216},
+		{"u2", 0, 1, 1184},
+	} {
+		s, err := m.Get(want.userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Wins != want.wins || s.Losses != want.losses || s.Rating != want.rating || s.Rated != 1 {
+			t.Errorf("got %+v; want %d wins, %d losses, rating %d", s, want.wins, want.losses, want.rating)
+		}
+	}
+}
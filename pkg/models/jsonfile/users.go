@@ -0,0 +1,82 @@
+package jsonfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rjpgt/battleship/pkg/models"
+	"github.com/rjpgt/battleship/pkg/models/memory"
+)
+
+// UserModel keeps accounts in memory and writes all of them to the
+// JSON file File whenever one is added.
+type UserModel struct {
+	*memory.UserModel
+	File string
+	mu   sync.Mutex // serializes writes to File
+}
+
+// OpenUsers creates the directory of file if needed and loads the
+// accounts saved in file, if it exists. The directory must not be the
+// one of a GameModel, which reads every JSON file in it as a game.
+func OpenUsers(file string) (*UserModel, error) {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return nil, err
+	}
+	m := &UserModel{UserModel: memory.NewUsers(), File: file}
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	users := []*models.User{}
+	err = json.Unmarshal(data, &users)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		err = m.UserModel.Insert(u)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Insert adds an account and saves all of them. The account is
+// taken out again if it cannot be saved.
+func (m *UserModel) Insert(u *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.UserModel.Insert(u)
+	if err != nil {
+		return err
+	}
+	err = m.save()
+	if err != nil {
+		m.UserModel.Remove(u.ID)
+		return err
+	}
+	return nil
+}
+
+// save writes the accounts to a temporary file first and renames it,
+// like games. The caller must hold m.mu.
+func (m *UserModel) save() error {
+	data, err := json.Marshal(m.UserModel.List())
+	if err != nil {
+		return err
+	}
+	tmp := m.File + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.File)
+}
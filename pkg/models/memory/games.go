@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// GameModel keeps games in a map in memory. They are lost
+// when the server stops. mu guards the map only; each game is
+// guarded by its own Mu.
+type GameModel struct {
+	mu    sync.RWMutex
+	games map[string]*models.Game
+}
+
+// New returns an empty GameModel
+func New() *GameModel {
+	return &GameModel{games: map[string]*models.Game{}}
+}
+
+// Get returns the game with the given ID
+func (m *GameModel) Get(id string) (*models.Game, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	pgame, ok := m.games[id]
+	return pgame, ok
+}
+
+// Put adds a game or replaces the game with the same ID
+func (m *GameModel) Put(pgame *models.Game) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.games[pgame.ID] = pgame
+	return nil
+}
+
+// Delete removes a game. Deleting an unknown game is not an error.
+func (m *GameModel) Delete(id string) error {
+	pgame, ok := m.Get(id)
+	if !ok {
+		return nil
+	}
+	pgame.Mu.Lock()
+	defer pgame.Mu.Unlock()
+	m.Forget(pgame)
+	return nil
+}
+
+// DeleteIf calls fn with the game's Mu held, and removes the game
+// before releasing it if fn returns true
+func (m *GameModel) DeleteIf(id string, fn func(*models.Game) bool) error {
+	return m.Update(id, func(pgame *models.Game) error {
+		if fn(pgame) {
+			m.Forget(pgame)
+		}
+		return nil
+	})
+}
+
+// Forget removes a game and marks it deleted, so that an Update or
+// View that was waiting for its Mu finds it gone. The caller must
+// hold pgame.Mu.
+func (m *GameModel) Forget(pgame *models.Game) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.games[pgame.ID] == pgame {
+		delete(m.games, pgame.ID)
+	}
+	pgame.Deleted = true
+}
+
+// List returns all the games in no particular order
+func (m *GameModel) List() []*models.Game {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	games := make([]*models.Game, 0, len(m.games))
+	for _, pgame := range m.games {
+		games = append(games, pgame)
+	}
+	return games
+}
+
+// Update calls fn with the game's Mu held. The changes fn makes to
+// the game stay even if it returns an error, so fn must check what
+// may fail before it changes anything.
+func (m *GameModel) Update(id string, fn func(*models.Game) error) error {
+	pgame, ok := m.Get(id)
+	if !ok {
+		return models.ErrNoGame
+	}
+	pgame.Mu.Lock()
+	defer pgame.Mu.Unlock()
+	if pgame.Deleted {
+		return models.ErrNoGame
+	}
+	return fn(pgame)
+}
+
+// View calls fn with the game's Mu held
+func (m *GameModel) View(id string, fn func(*models.Game) error) error {
+	return m.Update(id, fn)
+}
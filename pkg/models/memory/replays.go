@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// ReplayModel keeps replays in a map in memory.
+// They are lost when the server stops.
+type ReplayModel struct {
+	mu      sync.RWMutex
+	replays map[string]*models.Replay
+}
+
+// NewReplays returns an empty ReplayModel
+func NewReplays() *ReplayModel {
+	return &ReplayModel{replays: map[string]*models.Replay{}}
+}
+
+// Get returns the replay with the given ID
+func (m *ReplayModel) Get(id string) (*models.Replay, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.replays[id]
+	if !ok {
+		return nil, models.ErrNoReplay
+	}
+	return r, nil
+}
+
+// Put adds a replay or replaces the replay with the same ID
+func (m *ReplayModel) Put(r *models.Replay) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replays[r.ID] = r
+	return nil
+}
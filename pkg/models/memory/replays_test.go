@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+var _ models.ReplayStore = (*ReplayModel)(nil)
+
+func TestReplays(t *testing.T) {
+	m := NewReplays()
+	if _, err := m.Get("g1"); err != models.ErrNoReplay {
+		t.Fatalf("got %v; want ErrNoReplay", err)
+	}
+
+	first := &models.Replay{ID: "g1", Ruleset: "standard", Size: 10}
+	if err := m.Put(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Put(&models.Replay{ID: "g2", Ruleset: "small", Size: 8}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Get("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != first {
+		t.Errorf("got %+v; want %+v", got, first)
+	}
+
+	// a replay with the same ID replaces the first one
+	if err := m.Put(&models.Replay{ID: "g1", Ruleset: "classic", Size: 12}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = m.Get("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Ruleset != "classic" || got.Size != 12 {
+		t.Errorf("got %+v; want the classic replay", got)
+	}
+	if got, _ := m.Get("g2"); got == nil || got.Ruleset != "small" {
+		t.Errorf("got %+v; want the small replay", got)
+	}
+}
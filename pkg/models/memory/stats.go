@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// StatsModel keeps statistics in a map in memory, along with the IDs
+// of the games whose results are recorded. It hands out copies, so
+// that callers never see a record being updated.
+type StatsModel struct {
+	mu       sync.RWMutex
+	stats    map[string]*models.Stats
+	recorded map[string]bool // by game ID
+}
+
+// NewStats returns an empty StatsModel
+func NewStats() *StatsModel {
+	return &StatsModel{stats: map[string]*models.Stats{}, recorded: map[string]bool{}}
+}
+
+// Get returns a copy of the statistics of an account
+func (m *StatsModel) Get(userID string) (*models.Stats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.stats[userID]
+	if !ok {
+		return nil, models.ErrNoStats
+	}
+	dup := *s
+	return &dup, nil
+}
+
+// List returns copies of all the statistics in no particular order
+func (m *StatsModel) List() []*models.Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := make([]*models.Stats, 0, len(m.stats))
+	for _, s := range m.stats {
+		dup := *s
+		stats = append(stats, &dup)
+	}
+	return stats
+}
+
+// Recorded returns the IDs of the games whose results are recorded
+func (m *StatsModel) Recorded() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.recorded))
+	for id := range m.recorded {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Put adds statistics or replaces those of the same account, and
+// marks the results of the games gameIDs as recorded
+func (m *StatsModel) Put(stats []*models.Stats, gameIDs ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range stats {
+		m.stats[s.UserID] = s
+	}
+	for _, id := range gameIDs {
+		m.recorded[id] = true
+	}
+}
+
+// Record applies a result to the statistics of its players, unless
+// the result of the game is already recorded
+func (m *StatsModel) Record(r *models.Result) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	updated, ok := m.apply(r)
+	if !ok {
+		return nil
+	}
+	for _, s := range updated {
+		m.stats[s.UserID] = s
+	}
+	m.recorded[r.GameID] = true
+	return nil
+}
+
+// Apply gives the statistics of the players of the result as they are
+// once it is recorded, without recording it. ok is false if the result
+// of the game is already recorded. The caller must keep other calls to
+// Record and Put out until it puts them.
+func (m *StatsModel) Apply(r *models.Result) (updated []*models.Stats, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.apply(r)
+}
+
+// apply is Apply with m.mu held
+func (m *StatsModel) apply(r *models.Result) ([]*models.Stats, bool) {
+	if m.recorded[r.GameID] {
+		return nil, false
+	}
+	stats := map[string]*models.Stats{}
+	for _, pr := range r.Players {
+		if s, ok := m.stats[pr.UserID]; ok {
+			dup := *s
+			stats[pr.UserID] = &dup
+		}
+	}
+	r.Apply(stats)
+	updated := []*models.Stats{}
+	for _, s := range stats {
+		updated = append(updated, s)
+	}
+	return updated, true
+}
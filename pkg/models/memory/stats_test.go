@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+var _ models.StatsStore = (*StatsModel)(nil)
+
+func TestRecordOnce(t *testing.T) {
+	m := NewStats()
+	r := &models.Result{
+		GameID: "g1",
+		Players: [2]models.PlayerResult{
+			{UserID: "u1", Name: "alice", Shots: 20, Hits: 17},
+			{UserID: "u2", Name: "bobby", Shots: 19, Hits: 10},
+		},
+		Ranked: true,
+	}
+	for i := 0; i < 3; i++ {
+		if err := m.Record(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alice, err := m.Get("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Wins != 1 || alice.Shots != 20 || alice.Rated != 1 || alice.Rating != 1216 {
+		t.Errorf("got %+v for alice; want one game recorded", alice)
+	}
+	if _, ok := m.Apply(r); ok {
+		t.Error("Apply would record the game again")
+	}
+	if ids := m.Recorded(); len(ids) != 1 || ids[0] != "g1" {
+		t.Errorf("got recorded games %v; want g1", ids)
+	}
+
+	// another game is recorded
+	r.GameID = "g2"
+	if err := m.Record(r); err != nil {
+		t.Fatal(err)
+	}
+	alice, _ = m.Get("u1")
+	if alice.Wins != 2 || alice.Rated != 2 {
+		t.Errorf("got %+v for alice; want two games recorded", alice)
+	}
+	if _, err := m.Get("u3"); err != models.ErrNoStats {
+		t.Errorf("got %v; want ErrNoStats", err)
+	}
+}
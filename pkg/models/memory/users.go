@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/rjpgt/battleship/pkg/models"
+)
+
+// UserModel keeps accounts in maps in memory
+type UserModel struct {
+	mu    sync.RWMutex
+	users map[string]*models.User
+	names map[string]string // IDs by lower case name
+}
+
+// NewUsers returns an empty UserModel
+func NewUsers() *UserModel {
+	return &UserModel{users: map[string]*models.User{}, names: map[string]string{}}
+}
+
+// Get returns the account with the given ID
+func (m *UserModel) Get(id string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[id]
+	if !ok {
+		return nil, models.ErrNoUser
+	}
+	return u, nil
+}
+
+// GetByName returns the account with the given name in any case
+func (m *UserModel) GetByName(name string) (*models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[m.names[strings.ToLower(name)]]
+	if !ok {
+		return nil, models.ErrNoUser
+	}
+	return u, nil
+}
+
+// Insert adds an account whose name is not taken
+func (m *UserModel) Insert(u *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := strings.ToLower(u.Name)
+	if _, ok := m.names[name]; ok {
+		return models.ErrDuplicateName
+	}
+	m.users[u.ID] = u
+	m.names[name] = u.ID
+	return nil
+}
+
+// Remove takes an account out, undoing an Insert
+func (m *UserModel) Remove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		delete(m.names, strings.ToLower(u.Name))
+		delete(m.users, id)
+	}
+}
+
+// List returns all the accounts in no particular order
+func (m *UserModel) List() []*models.User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	users := make([]*models.User, 0, len(m.users))
+	for _, u := range m.users {
+		users = append(users, u)
+	}
+	return users
+}
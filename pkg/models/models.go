@@ -2,38 +2,40 @@ package models
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"net/url"
-	"strconv"
-	"strings"
 	"sync"
+	"time"
 )
 
 // ShipPart is made of a location, Pos,
 //+ and an image for that location
 type ShipPart struct {
-	Pos [2]int
+	Pos Coord
 	Img string
 }
 
-// ShipT is the battleship type
+// ShipT is the battleship type. Parts holds the parts not
+// hit yet, Squares all the squares of the ship.
 type ShipT struct {
-	Class string
-	Parts map[int]ShipPart
+	Class   string
+	Parts   map[int]ShipPart
+	Squares []Coord
 }
 
-func NewShip(class string, field string) *ShipT {
-	squares := strings.Split(field, ",")
-	nums := make([]int, len(squares))
-	posns := make([][2]int, len(squares))
-	for i := range squares {
-		nums[i], _ = strconv.Atoi(strings.TrimSpace(squares[i]))
-		posns[i] = [2]int{nums[i] / 10, nums[i] % 10}
+func NewShip(class string, field string) (*ShipT, error) {
+	posns, err := ParseCoords(field)
+	if err != nil {
+		return nil, err
+	}
+	if len(posns) < 2 {
+		return nil, ErrBadSquare
 	}
 	rowImageNames := [3]string{"end_left", "mid_h", "end_right"}
 	colImageNames := [3]string{"end_top", "mid_v", "end_bottom"}
 	var imageNames [3]string
-	if posns[0][0] == posns[1][0] {
+	if posns[0].Row == posns[1].Row {
 		imageNames = rowImageNames
 	} else {
 		imageNames = colImageNames
@@ -45,69 +47,120 @@ func NewShip(class string, field string) *ShipT {
 		parts[i] = ShipPart{Pos: posns[i], Img: imageNames[1]}
 	}
 
-	return &ShipT{Class: class, Parts: parts}
+	return &ShipT{Class: class, Parts: parts, Squares: posns}, nil
 }
 
 // Player represents a battleship game player
 type Player struct {
-	// array zero value is not nil unlike that of slice
-	//+ so need not explicitly initialize it.
-	Board      [10][10]string
+	Board      [][]string
+	Bot        bool              // played by the computer
+	Fleet      map[string]string // squares of each ship as placed, by ship field
+	Level      string            // difficulty level of a computer player
 	FlashMsg   string
 	ID         string
-	MsgChn     chan string
 	NickName   string
 	OpponentID string
 	//Ships      [5]ShipT
 	Ships      map[int]*ShipT
-	Shots      [][2]int
-	ShotsBoard [10][10]string
+	Shots      []Shot
+	ShotsBoard [][]string
 	StatusMsgs []string
+	Token      string // authenticates the player to the API
+	UserID     string // account of the player, empty for a guest
 }
 
-func NewPlayer(formFields url.Values) (*Player, error) {
+// NewPlayer makes a player with the fleet of the ruleset
+// placed as given in the form
+func NewPlayer(formFields url.Values, rules Ruleset) (*Player, error) {
 	id, err := fakeUUID()
 	if err != nil {
 		return nil, err
 	}
-	btlship := NewShip("battleship", formFields.Get("btlship"))
-	cruiser := NewShip("cruiser", formFields.Get("cruiser"))
-	frigate := NewShip("frigate", formFields.Get("frigate"))
-	destroyer := NewShip("destroyer", formFields.Get("destroyer"))
-	patrolboat := NewShip("patrolboat", formFields.Get("patrolboat"))
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
 
 	player := Player{
-		ID:       id,
-		MsgChn:   make(chan string, 1),
-		NickName: formFields.Get("username"),
-		Ships:    map[int]*ShipT{0: btlship, 1: cruiser, 2: frigate, 3: destroyer, 4: patrolboat},
-		Shots:    [][2]int{},
+		Board:      newBoard(rules.Size),
+		Fleet:      map[string]string{},
+		ID:         id,
+		NickName:   formFields.Get("username"),
+		Ships:      map[int]*ShipT{},
+		Shots:      []Shot{},
+		ShotsBoard: newBoard(rules.Size),
+		Token:      token,
+	}
+	for i, ship := range rules.Ships {
+		pship, err := NewShip(ship.Name, formFields.Get(ship.Field))
+		if err != nil {
+			return nil, err
+		}
+		player.Ships[i] = pship
+		player.Fleet[ship.Field] = formFields.Get(ship.Field)
 	}
 	for _, pship := range player.Ships {
 		for _, shipPart := range pship.Parts {
 			posn := shipPart.Pos
-			player.Board[posn[0]][posn[1]] = shipPart.Img
+			if !posn.On(rules.Size) {
+				return nil, ErrOffBoard
+			}
+			player.Board[posn.Row][posn.Col] = shipPart.Img
 		}
 	}
 
 	return &player, nil
 }
 
-// Game represents a battleship game
+// Game represents a battleship game.
+//
+// Locking: a GameStore guards its own index of games, so its methods
+// may be called from any goroutine. Mu guards every field of the game
+// except ID, including its Players and everything they hold. Handlers
+// touch a game only inside GameStore.Update, View or DeleteIf, which
+// hold Mu while their callback runs. The callbacks must not call back
+// into the store for the same game. Events is created with the game and
+// never replaced while the game is in a store, and has its own lock, so
+// it may be used without holding Mu.
 type Game struct {
-	ID         string
-	Mu         sync.Mutex
-	NextToPlay string
-	Players    map[string]*Player
-	Status     int //0 - starting, 1 - playing, 2 - ended
+	Active      time.Time     // when a player last joined, fired, chatted, forfeited or asked for a rematch
+	BestOf      int           // length of the series of rematches, 0 if open-ended
+	Chat        []ChatMessage // the last MaxChatLog messages of the players
+	ChatMuted   bool          // the owner muted the chat
+	Clock       TimeControl
+	ClockLeft   map[string]time.Duration // time left on the game clock of each player, with Clock.PerGame
+	Created     time.Time                // zero for games saved before it was kept
+	Deleted     bool                     `json:"-"` // the game was removed from its store
+	Events      *Bus                     `json:"-"`
+	Forfeited   string                   // ID of the player who lost the game by forfeit
+	ID          string
+	Mu          sync.Mutex `json:"-"`
+	NextID      string     // ID of the rematch of the game, once made
+	NextToPlay  string
+	Owner       string // ID of the player who created the game
+	Players     map[string]*Player
+	Presence    map[string]*Presence      `json:"-"` // the pages the players have open, by player ID
+	PrevID      string                    // ID of the game this game is a rematch of
+	Private     bool                      // the game is joined by its link only, and not listed in the lobby
+	Ranked      bool                      // the game moves the ratings of its players
+	Rematch     map[string]RematchRequest // rematch requests, by player ID
+	Rules       Ruleset
+	Spectators  bool           // spectators may watch the game
+	Status      int            // GameStarting, GamePlaying or GameEnded
+	Timer       *time.Timer    `json:"-"` // fires when the player to play runs out of time
+	TurnStarted time.Time      // when the player to play started the turn
+	Turns       int            // number of shots fired so far
+	Wins        map[string]int // games of the series won, by player ID
 }
 
-func NewGame(formFields url.Values) (*Game, error) {
+// NewGame makes a game played by the ruleset
+// with its creator as the only player
+func NewGame(formFields url.Values, rules Ruleset) (*Game, error) {
 	id, err := fakeUUID()
 	if err != nil {
 		return nil, err
 	}
-	pplayer, err := NewPlayer(formFields)
+	pplayer, err := NewPlayer(formFields, rules)
 	if err != nil {
 		return nil, err
 	}
@@ -116,19 +169,105 @@ func NewGame(formFields url.Values) (*Game, error) {
 		fmt.Sprintf("Invite opponent to %s.", joinURL),
 		"Waiting for opponent to join.",
 	}
+	now := time.Now()
 	game := Game{
+		Active:     now,
+		Created:    now,
+		Events:     NewBus(),
 		ID:         id,
 		Players:    map[string]*Player{},
 		NextToPlay: pplayer.ID,
-		Status:     0,
+		Owner:      pplayer.ID,
+		Rules:      rules,
+		Status:     GameStarting,
 	}
 	game.Players[pplayer.ID] = pplayer
 	return &game, nil
 }
 
-// GameModel stores all games
-type GameModel struct {
-	Games map[string]*Game
+// Join adds the second player to the game and starts it, with its
+// clocks.
+// The caller must hold g.Mu.
+func (g *Game) Join(pplayer2 *Player) {
+	for _, pplayer1 := range g.Players {
+		pplayer1.OpponentID = pplayer2.ID
+		pplayer2.OpponentID = pplayer1.ID
+	}
+	g.Players[pplayer2.ID] = pplayer2
+	g.Status = GamePlaying
+	g.Active = time.Now()
+	g.startClock(g.Active)
+}
+
+// IdleFor tells how long the game has gone without a player joining,
+// firing, chatting, forfeiting or asking for a rematch. The caller must
+// hold g.Mu.
+func (g *Game) IdleFor(now time.Time) time.Duration {
+	return now.Sub(g.Active)
+}
+
+// AddBot adds a computer player of the given difficulty level with a
+// random fleet to the game and starts it. The caller must hold g.Mu.
+func (g *Game) AddBot(level string) (*Player, error) {
+	formFields, err := RandomFleet(g.Rules)
+	if err != nil {
+		return nil, err
+	}
+	formFields.Set("username", "Computer")
+	pbot, err := NewPlayer(formFields, g.Rules)
+	if err != nil {
+		return nil, err
+	}
+	pbot.Bot = true
+	pbot.Level = level
+	g.Join(pbot)
+	return pbot, nil
+}
+
+// Joinable tells if the game waits for a second player. A game that
+// ended is never joined again, even once a player has left it. The
+// caller must hold g.Mu.
+func (g *Game) Joinable() bool {
+	return g.Status == GameStarting && len(g.Players) == 1
+}
+
+// Listed tells if the game is listed in the lobby: it waits for a
+// second player and is not private. The caller must hold g.Mu.
+func (g *Game) Listed() bool {
+	return g.Joinable() && !g.Private
+}
+
+// Restore sets up the fields of a game that are not kept
+// by a store, after the game has been loaded from it.
+func (g *Game) Restore() {
+	// games saved before rulesets could be chosen
+	if len(g.Rules.Ships) == 0 {
+		g.Rules = LookupRuleset("standard")
+		for _, pplayer := range g.Players {
+			g.Rules.Size = len(pplayer.Board)
+		}
+	}
+	g.Events = NewBus()
+}
+
+// ErrNoGame is returned by a GameStore for an unknown game ID
+var ErrNoGame = errors.New("models: no such game")
+
+// GameStore is implemented by the stores that keep games.
+// Update calls fn with the game's Mu held and saves the game
+// if fn returns nil. View calls fn with the game's Mu held
+// and saves nothing, so fn must not change the game. DeleteIf
+// calls fn with the game's Mu held and removes the game before
+// releasing it if fn returns true, so no change made in between
+// is lost; fn must not change a game it keeps.
+type GameStore interface {
+	Get(id string) (*Game, bool)
+	Put(g *Game) error
+	Delete(id string) error
+	DeleteIf(id string, fn func(*Game) bool) error
+	List() []*Game
+	Update(id string, fn func(*Game) error) error
+	View(id string, fn func(*Game) error) error
 }
 
 func fakeUUID() (string, error) {
@@ -139,3 +278,13 @@ func fakeUUID() (string, error) {
 	}
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
 }
+
+// newToken makes a random secret for a player to send to the API
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// Presence tells if a player has the page of a game open. The pages
+// are counted by their event streams, which close when a page is
+// closed, reloaded or loses its connection.
+type Presence struct {
+	Pages int         // event streams open to the player's pages
+	Since time.Time   // when the last of them closed, once Pages is 0
+	Away  bool        // the opponent was told the player has gone
+	Timer *time.Timer // checks on the player while Pages is 0
+}
+
+// PageOpened records that a page of the player opened its event
+// stream, and tells if the opponent had been told the player has
+// gone. The caller must hold g.Mu.
+func (g *Game) PageOpened(playerID string) (back bool) {
+	if g.Presence == nil {
+		g.Presence = map[string]*Presence{}
+	}
+	ppresence, ok := g.Presence[playerID]
+	if !ok {
+		ppresence = &Presence{}
+		g.Presence[playerID] = ppresence
+	}
+	if ppresence.Timer != nil {
+		ppresence.Timer.Stop()
+		ppresence.Timer = nil
+	}
+	back = ppresence.Away
+	ppresence.Pages++
+	ppresence.Away = false
+	return back
+}
+
+// PageClosed records that the event stream of a page of the player
+// closed, and tells if the player has no page of the game open left.
+// The caller must hold g.Mu.
+func (g *Game) PageClosed(playerID string, now time.Time) (gone bool) {
+	ppresence, ok := g.Presence[playerID]
+	if !ok || ppresence.Pages == 0 {
+		return false
+	}
+	ppresence.Pages--
+	if ppresence.Pages > 0 {
+		return false
+	}
+	ppresence.Since = now
+	return true
+}
+
+// AwayFor tells how long the player has had no page of the game open.
+// It returns false if the player has a page open, or never opened one
+// since the server started. The caller must hold g.Mu.
+func (g *Game) AwayFor(playerID string, now time.Time) (time.Duration, bool) {
+	ppresence, ok := g.Presence[playerID]
+	if !ok || ppresence.Pages > 0 {
+		return 0, false
+	}
+	return now.Sub(ppresence.Since), true
+}
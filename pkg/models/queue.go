@@ -0,0 +1,206 @@
+package models
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MatchRatingGap is the largest difference in rating between two
+// players paired by rating
+const MatchRatingGap = 200
+
+// Seeker is a player waiting in the matchmaking queue for an opponent.
+// Form is the player's new game form, with a fleet placed by Rules.
+type Seeker struct {
+	ID          string // kept in the player's session while waiting
+	Form        url.Values
+	Rules       Ruleset
+	UserID      string // empty for a guest
+	Rating      int    // InitialRating for a guest
+	Ranked      bool   // pair only with players who want a ranked game
+	AnyRules    bool   // pair with players of any ruleset
+	CloseRating bool   // pair only with players of a close rating
+	Joined      time.Time
+	Events      *Bus // tells the waiting page once the player is paired
+
+	// set once the player is paired, for the waiting page to move on
+	GameID   string
+	PlayerID string
+	Failed   bool // the game could not be made
+
+	pages int // waiting pages open
+}
+
+// NewSeeker makes a seeker for the new game form of a player
+func NewSeeker(form url.Values, rules Ruleset, userID string, rating int) (*Seeker, error) {
+	id, err := fakeUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &Seeker{
+		ID:     id,
+		Form:   form,
+		Rules:  rules,
+		UserID: userID,
+		Rating: rating,
+		Joined: time.Now(),
+		Events: NewBus(),
+	}, nil
+}
+
+// SameRules tells if the two seekers chose the same ruleset and board
+func (s *Seeker) SameRules(o *Seeker) bool {
+	return s.Rules.Name == o.Rules.Name && s.Rules.Size == o.Rules.Size
+}
+
+// pairs tells if the two seekers may play each other
+func (s *Seeker) pairs(o *Seeker) bool {
+	if s.Ranked != o.Ranked {
+		return false
+	}
+	if s.UserID != "" && s.UserID == o.UserID {
+		return false
+	}
+	if !s.SameRules(o) && !(s.AnyRules || o.AnyRules) {
+		return false
+	}
+	gap := s.Rating - o.Rating
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= MatchRatingGap || !(s.CloseRating || o.CloseRating)
+}
+
+// Queue is the matchmaking queue. It keeps the seekers waiting, oldest
+// first, and the seekers that were paired until their waiting page has
+// moved on to the game or was left closed. A Queue has its own lock
+// and is kept in memory only.
+type Queue struct {
+	mu      sync.Mutex
+	waiting []*Seeker
+	seekers map[string]*Seeker // waiting and paired, by ID
+}
+
+// NewQueue makes an empty queue
+func NewQueue() *Queue {
+	return &Queue{seekers: map[string]*Seeker{}}
+}
+
+// Add pairs the seeker with the seeker that has waited longest among
+// those it may play, taking that one out of the queue. If there is
+// none, the seeker waits in the queue and Add returns nil.
+func (q *Queue) Add(s *Seeker) *Seeker {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, o := range q.waiting {
+		if s.pairs(o) {
+			q.waiting = append(q.waiting[:i:i], q.waiting[i+1:]...)
+			return o
+		}
+	}
+	q.waiting = append(q.waiting, s)
+	q.seekers[s.ID] = s
+	return nil
+}
+
+// Get returns a copy of the seeker with the given ID
+func (q *Queue) Get(id string) (Seeker, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s, ok := q.seekers[id]
+	if !ok {
+		return Seeker{}, false
+	}
+	return *s, true
+}
+
+// Paired records the game made for a seeker taken out of the queue by
+// Add, or that it could not be made if gameID is empty, and tells the
+// seeker's waiting page
+func (q *Queue) Paired(id, gameID, playerID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s, ok := q.seekers[id]
+	if !ok {
+		return
+	}
+	s.GameID, s.PlayerID = gameID, playerID
+	s.Failed = gameID == ""
+	s.Events.Publish(Event{Type: EventMatched, GameID: gameID})
+}
+
+// Cancel takes a seeker that is still waiting out of the queue, and
+// reports whether it was waiting. A seeker that was paired is kept
+// for its page to move on to the game.
+func (q *Queue) Cancel(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.cancel(id)
+}
+
+// cancel is Cancel with q.mu held
+func (q *Queue) cancel(id string) bool {
+	for i, s := range q.waiting {
+		if s.ID == id {
+			q.waiting = append(q.waiting[:i:i], q.waiting[i+1:]...)
+			delete(q.seekers, id)
+			return true
+		}
+	}
+	return false
+}
+
+// PageOpened counts a waiting page of the seeker as open
+func (q *Queue) PageOpened(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if s, ok := q.seekers[id]; ok {
+		s.pages++
+	}
+}
+
+// PageClosed counts a waiting page of the seeker as closed
+func (q *Queue) PageClosed(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if s, ok := q.seekers[id]; ok {
+		s.pages--
+	}
+}
+
+// CancelIdle takes out a seeker with no waiting page open, whether
+// still waiting or paired, as a paired seeker whose page was closed
+// would never move on to the game. It reports whether the seeker was
+// waiting.
+func (q *Queue) CancelIdle(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if s, ok := q.seekers[id]; !ok || s.pages > 0 {
+		return false
+	}
+	if q.cancel(id) {
+		return true
+	}
+	delete(q.seekers, id)
+	return false
+}
+
+// Forget takes a paired seeker out once its page has moved on
+func (q *Queue) Forget(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, s := range q.waiting {
+		if s.ID == id {
+			return
+		}
+	}
+	delete(q.seekers, id)
+}
+
+// Len returns the number of seekers waiting
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
@@ -0,0 +1,179 @@
+package models
+
+import "testing"
+
+// seeker makes a seeker of a standard game with the initial rating
+func seeker(t *testing.T, userID string) *Seeker {
+	t.Helper()
+	s, err := NewSeeker(nil, Rulesets[0], userID, InitialRating)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSeekerPairs(t *testing.T) {
+	small := LookupRuleset("small")
+	big := Rulesets[0]
+	big.Size = 12
+
+	tests := []struct {
+		name string
+		s, o func(s *Seeker)
+		want bool
+	}{
+		{"casual players", nil, nil, true},
+		{"both ranked", func(s *Seeker) { s.Ranked = true }, func(s *Seeker) { s.Ranked = true }, true},
+		{"ranked and casual", func(s *Seeker) { s.Ranked = true }, nil, false},
+		{"casual and ranked", nil, func(s *Seeker) { s.Ranked = true }, false},
+		{"same account", func(s *Seeker) { s.UserID = "u1" }, func(s *Seeker) { s.UserID = "u1" }, false},
+		{"two guests", func(s *Seeker) { s.UserID = "" }, func(s *Seeker) { s.UserID = "" }, true},
+		{"other ruleset", func(s *Seeker) { s.Rules = small }, nil, false},
+		{"other board size", func(s *Seeker) { s.Rules = big }, nil, false},
+		{"other ruleset, any rules", func(s *Seeker) { s.Rules = small; s.AnyRules = true }, nil, true},
+		{"other ruleset, opponent takes any rules", func(s *Seeker) { s.Rules = small }, func(s *Seeker) { s.AnyRules = true }, true},
+		{"far ratings", func(s *Seeker) { s.Rating = 1600 }, nil, true},
+		{"gap of 200, close rating", func(s *Seeker) { s.Rating = 1400; s.CloseRating = true }, nil, true},
+		{"gap of 201, close rating", func(s *Seeker) { s.Rating = 1401; s.CloseRating = true }, nil, false},
+		{"gap of 201 below, close rating", func(s *Seeker) { s.Rating = 999; s.CloseRating = true }, nil, false},
+		{"gap of 201, opponent wants close rating", func(s *Seeker) { s.Rating = 1401 }, func(s *Seeker) { s.CloseRating = true }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, o := seeker(t, "u1"), seeker(t, "u2")
+			if tt.s != nil {
+				tt.s(s)
+			}
+			if tt.o != nil {
+				tt.o(o)
+			}
+			if got := s.pairs(o); got != tt.want {
+				t.Errorf("s.pairs(o) = %t; want %t", got, tt.want)
+			}
+			if got := o.pairs(s); got != tt.want {
+				t.Errorf("o.pairs(s) = %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueAdd(t *testing.T) {
+	q := NewQueue()
+	ranked := seeker(t, "u1")
+	ranked.Ranked = true
+	// first and second may both play a newcomer of 1200, but not
+	// each other
+	first, second := seeker(t, "u2"), seeker(t, "u3")
+	first.Rating = 1500
+	second.Rating = 1000
+	second.CloseRating = true
+	for _, s := range []*Seeker{ranked, first, second} {
+		if o := q.Add(s); o != nil {
+			t.Fatalf("%s was paired with %s", s.UserID, o.UserID)
+		}
+	}
+	if q.Len() != 3 {
+		t.Fatalf("%d seekers waiting; want 3", q.Len())
+	}
+
+	// the one that waited longest among those it may play
+	if o := q.Add(seeker(t, "u4")); o != first {
+		t.Errorf("paired with %v; want u2", o)
+	}
+	if q.Len() != 2 {
+		t.Errorf("%d seekers waiting; want 2", q.Len())
+	}
+	// the paired seeker is kept until its page moves on
+	q.Paired(first.ID, "g1", "p1")
+	s, ok := q.Get(first.ID)
+	if !ok || s.GameID != "g1" || s.PlayerID != "p1" || s.Failed {
+		t.Errorf("got %+v, %t; want paired to g1", s, ok)
+	}
+	q.Forget(first.ID)
+	if _, ok := q.Get(first.ID); ok {
+		t.Error("a forgotten seeker is still kept")
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	q := NewQueue()
+	waiting, paired := seeker(t, "u1"), seeker(t, "u2")
+	paired.Ranked = true
+	q.Add(waiting)
+	q.Add(paired)
+	opponent := seeker(t, "u3")
+	opponent.Ranked = true
+	if o := q.Add(opponent); o != paired {
+		t.Fatalf("paired with %v; want u2", o)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"waiting", waiting.ID, true},
+		{"cancelled already", waiting.ID, false},
+		{"paired", paired.ID, false},
+		{"unknown", "nobody", false},
+	}
+	for _, tt := range tests {
+		if got := q.Cancel(tt.id); got != tt.want {
+			t.Errorf("%s: Cancel = %t; want %t", tt.name, got, tt.want)
+		}
+	}
+	if q.Len() != 0 {
+		t.Errorf("%d seekers waiting; want 0", q.Len())
+	}
+	if _, ok := q.Get(paired.ID); !ok {
+		t.Error("Cancel took out a paired seeker")
+	}
+}
+
+func TestQueueCancelIdle(t *testing.T) {
+	q := NewQueue()
+	s := seeker(t, "u1")
+	q.Add(s)
+
+	q.PageOpened(s.ID)
+	q.PageOpened(s.ID)
+	q.PageClosed(s.ID)
+	if q.CancelIdle(s.ID) {
+		t.Fatal("cancelled a seeker with a page open")
+	}
+	q.PageClosed(s.ID)
+	if !q.CancelIdle(s.ID) {
+		t.Fatal("kept a seeker with no page open")
+	}
+	if q.CancelIdle(s.ID) || q.Len() != 0 {
+		t.Error("cancelled a seeker twice")
+	}
+	if q.CancelIdle("nobody") {
+		t.Error("cancelled an unknown seeker")
+	}
+}
+
+func TestQueueCancelIdlePaired(t *testing.T) {
+	q := NewQueue()
+	s := seeker(t, "u1")
+	q.Add(s)
+	if o := q.Add(seeker(t, "u2")); o != s {
+		t.Fatalf("paired with %v; want u1", o)
+	}
+	q.Paired(s.ID, "g1", "p1")
+
+	q.PageOpened(s.ID)
+	q.CancelIdle(s.ID)
+	if _, ok := q.Get(s.ID); !ok {
+		t.Fatal("took out a paired seeker with a page open")
+	}
+	// the page was closed without moving on to the game
+	q.PageClosed(s.ID)
+	if q.CancelIdle(s.ID) {
+		t.Error("a paired seeker was reported waiting")
+	}
+	if _, ok := q.Get(s.ID); ok {
+		t.Error("a paired seeker with no page open is still kept")
+	}
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SeriesLengths are the lengths of series of rematches a player may
+// ask for, 0 being an open-ended series
+var SeriesLengths = []int{0, 3, 5}
+
+// ErrGameNotOver is returned by AskRematch for a game still in progress
+var ErrGameNotOver = errors.New("models: game is not over")
+
+// RematchRequest is what a player asks for with a rematch
+type RematchRequest struct {
+	SameFleet bool // keep the fleet as placed, or else place it at random
+	BestOf    int  // length of the series if the rematch starts a new one
+}
+
+// SeriesOver tells if a player has won the series the game is part of
+func (g *Game) SeriesOver() bool {
+	for _, wins := range g.Wins {
+		if g.BestOf > 0 && wins > g.BestOf/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// AskRematch records that the player wants a rematch. Computer players
+// always want one. Once both players want one the rematch is made, the
+// game links to it in NextID and the rematch is returned; until then
+// the returned game is nil. The caller must hold g.Mu.
+func (g *Game) AskRematch(playerID string, req RematchRequest) (*Game, error) {
+	if g.Status != GameEnded {
+		return nil, ErrGameNotOver
+	}
+	pplayer, ok := g.Players[playerID]
+	if !ok {
+		return nil, ErrNoSuchPlayer
+	}
+	popponent, ok := g.Players[pplayer.OpponentID]
+	if !ok {
+		return nil, ErrNoSuchPlayer
+	}
+	if g.NextID != "" {
+		return nil, nil
+	}
+	if g.Rematch == nil {
+		g.Rematch = map[string]RematchRequest{}
+	}
+	g.Rematch[playerID] = req
+	g.Active = time.Now()
+	if popponent.Bot {
+		g.Rematch[popponent.ID] = RematchRequest{}
+	}
+	if len(g.Rematch) < 2 {
+		return nil, nil
+	}
+	return g.newRematch()
+}
+
+// newRematch makes the rematch of the game. The players keep their IDs
+// and tokens, and the player who fired second now fires first.
+func (g *Game) newRematch() (*Game, error) {
+	id, err := fakeUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	next := &Game{
+		Active:     now,
+		ChatMuted:  g.ChatMuted,
+		Clock:      g.Clock,
+		Created:    now,
+		Events:     NewBus(),
+		ID:         id,
+		Owner:      g.Owner,
+		Players:    map[string]*Player{},
+		PrevID:     g.ID,
+		Private:    g.Private,
+		Ranked:     g.Ranked,
+		Rules:      g.Rules,
+		Spectators: g.Spectators,
+		Status:     GamePlaying,
+		Wins:       map[string]int{},
+	}
+
+	// a series goes on until a player has won it. A new series counts
+	// the game just played if it was the first between the players.
+	switch {
+	case g.PrevID != "" && !g.SeriesOver():
+		next.BestOf = g.BestOf
+		for id, wins := range g.Wins {
+			next.Wins[id] = wins
+		}
+	default:
+		for _, req := range g.Rematch {
+			if req.BestOf > next.BestOf {
+				next.BestOf = req.BestOf
+			}
+		}
+		if g.PrevID == "" {
+			for id, wins := range g.Wins {
+				next.Wins[id] = wins
+			}
+		}
+	}
+
+	for id, pplayer := range g.Players {
+		formFields := url.Values{}
+		if g.Rematch[id].SameFleet {
+			for field, posns := range pplayer.Fleet {
+				formFields.Set(field, posns)
+			}
+		} else {
+			var err error
+			formFields, err = RandomFleet(g.Rules)
+			if err != nil {
+				return nil, err
+			}
+		}
+		formFields.Set("username", pplayer.NickName)
+		pnext, err := NewPlayer(formFields, g.Rules)
+		if err != nil {
+			return nil, err
+		}
+		pnext.ID = pplayer.ID
+		pnext.Token = pplayer.Token
+		pnext.Bot = pplayer.Bot
+		pnext.Level = pplayer.Level
+		pnext.OpponentID = pplayer.OpponentID
+		pnext.UserID = pplayer.UserID
+		next.Players[id] = pnext
+	}
+
+	first := g.Players[g.firstPlayer()].OpponentID
+	next.NextToPlay = first
+	next.startClock(next.Created)
+	for id, pplayer := range next.Players {
+		if id == first {
+			pplayer.StatusMsgs = []string{"Rematch! It's your turn to play."}
+		} else if next.Players[first].Bot {
+			pplayer.StatusMsgs = []string{fmt.Sprintf("Rematch! The %s fires first.", next.Players[first].NickName)}
+		} else {
+			pplayer.StatusMsgs = []string{fmt.Sprintf("Rematch! Waiting for %s to play.", next.Players[first].NickName)}
+		}
+	}
+	g.NextID = next.ID
+	return next, nil
+}
+
+// firstPlayer returns the ID of the player who fired the first
+// shot of the game, or who is to fire it. The caller must hold g.Mu.
+func (g *Game) firstPlayer() string {
+	first := g.NextToPlay
+	for id, pplayer := range g.Players {
+		if len(pplayer.Shots) > 0 && pplayer.Shots[0].Turn == 1 {
+			first = id
+		}
+	}
+	return first
+}
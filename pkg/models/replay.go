@@ -0,0 +1,187 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ReplayError is returned for a replay record that does not
+// describe a game that could have been played
+type ReplayError struct {
+	Reason string
+}
+
+func (e *ReplayError) Error() string {
+	return "models: invalid replay: " + e.Reason
+}
+
+// ErrNoReplay is returned by a ReplayStore for an unknown replay ID
+var ErrNoReplay = errors.New("models: no such replay")
+
+// Replay is the record of a game: the fleets as they were placed and
+// the shots in the order they were fired. Players[0] fired first.
+type Replay struct {
+	ID      string         `json:"id"`
+	Ruleset string         `json:"ruleset"`
+	Size    int            `json:"size"`
+	Players []ReplayPlayer `json:"players"`
+	Shots   []ReplayShot   `json:"shots"`
+	Time    time.Time      `json:"time"` // when the record was made
+}
+
+// ReplayPlayer is a player of a replay with the fleet
+// as placed, in the notation of the new game form
+type ReplayPlayer struct {
+	NickName string            `json:"nickname"`
+	Bot      bool              `json:"bot,omitempty"`
+	Fleet    map[string]string `json:"fleet"`
+}
+
+// ReplayShot is a shot of a replay. Player is the index
+// in the replay's Players of the player who fired it.
+type ReplayShot struct {
+	Player int    `json:"p"`
+	Square string `json:"sq"`
+}
+
+// Replay makes the replay record of the game. The caller must hold g.Mu.
+func (g *Game) Replay() *Replay {
+	r := &Replay{
+		ID:      g.ID,
+		Ruleset: g.Rules.Name,
+		Size:    g.Rules.Size,
+		Players: []ReplayPlayer{},
+		Shots:   []ReplayShot{},
+		Time:    time.Now(),
+	}
+
+	first := g.firstPlayer()
+	for _, id := range []string{first, g.Players[first].OpponentID} {
+		pplayer, ok := g.Players[id]
+		if !ok {
+			continue
+		}
+		r.Players = append(r.Players, ReplayPlayer{
+			NickName: pplayer.NickName,
+			Bot:      pplayer.Bot,
+			Fleet:    pplayer.Fleet,
+		})
+	}
+
+	for _, pplayer := range g.Players {
+		for _, shot := range pplayer.Shots {
+			for len(r.Shots) < shot.Turn {
+				r.Shots = append(r.Shots, ReplayShot{})
+			}
+			seat := 0
+			if pplayer.ID != first {
+				seat = 1
+			}
+			r.Shots[shot.Turn-1] = ReplayShot{Player: seat, Square: shot.Square()}
+		}
+	}
+	return r
+}
+
+// Rules returns the ruleset the replayed game was played by
+func (r *Replay) Rules() (Ruleset, error) {
+	for _, rules := range Rulesets {
+		if rules.Name == r.Ruleset {
+			if !ValidSize(r.Size) {
+				return rules, &ReplayError{fmt.Sprintf("board size %d", r.Size)}
+			}
+			rules.Size = r.Size
+			return rules, nil
+		}
+	}
+	return Ruleset{}, &ReplayError{fmt.Sprintf("unknown ruleset %q", r.Ruleset)}
+}
+
+// Fleets makes the players of the replay with their fleets as placed
+func (r *Replay) Fleets() ([]*Player, error) {
+	rules, err := r.Rules()
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Players) != 2 {
+		return nil, &ReplayError{"a game has two players"}
+	}
+	players := []*Player{}
+	for _, rp := range r.Players {
+		formFields := url.Values{}
+		formFields.Set("username", rp.NickName)
+		for field, posns := range rp.Fleet {
+			formFields.Set(field, posns)
+		}
+		pplayer, err := NewPlayer(formFields, rules)
+		if err != nil {
+			return nil, &ReplayError{fmt.Sprintf("fleet of %s: %v", rp.NickName, err)}
+		}
+		pplayer.Bot = rp.Bot
+		players = append(players, pplayer)
+	}
+	return players, nil
+}
+
+// Play plays the shots of the replay on a new game made from the
+// fleets and returns their results, checking that every shot could
+// have been fired.
+func (r *Replay) Play() ([]ShotResult, error) {
+	players, err := r.Fleets()
+	if err != nil {
+		return nil, err
+	}
+	rules, _ := r.Rules()
+	g := &Game{
+		Players:    map[string]*Player{players[0].ID: players[0]},
+		NextToPlay: players[0].ID,
+		Rules:      rules,
+	}
+	g.Join(players[1])
+
+	results := []ShotResult{}
+	for i, shot := range r.Shots {
+		if shot.Player != 0 && shot.Player != 1 {
+			return nil, &ReplayError{fmt.Sprintf("shot %d: no player %d", i+1, shot.Player)}
+		}
+		pos, err := ParseCoord(shot.Square)
+		if err != nil {
+			return nil, &ReplayError{fmt.Sprintf("shot %d: %v", i+1, err)}
+		}
+		result, err := g.Fire(players[shot.Player].ID, pos)
+		if err != nil {
+			return nil, &ReplayError{fmt.Sprintf("shot %d: %v", i+1, err)}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ImportReplay reads a replay record exported as JSON and checks it
+// by playing it; a record that cannot be played gives a *ReplayError.
+// The replay gets a new ID, so an import never replaces another one.
+func ImportReplay(data []byte) (*Replay, error) {
+	r := &Replay{}
+	err := json.Unmarshal(data, r)
+	if err != nil {
+		return nil, &ReplayError{err.Error()}
+	}
+	_, err = r.Play()
+	if err != nil {
+		return nil, err
+	}
+	r.ID, err = fakeUUID()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReplayStore is implemented by the stores that keep replays
+type ReplayStore interface {
+	Get(id string) (*Replay, error)
+	Put(r *Replay) error
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"math/rand"
+	"net/url"
+	"strings"
+)
+
+// ShipClass is a kind of ship in a fleet. Field is the name of the
+// new game form field that holds the ship's squares.
+type ShipClass struct {
+	Name  string
+	Field string
+	Size  int
+}
+
+// Ruleset tells the board size, the fleet each player places and
+// the shot rules of a game. With ExtraShotOnHit a player who hits a
+// ship fires again.
+type Ruleset struct {
+	Name           string
+	Label          string
+	Size           int
+	Ships          []ShipClass
+	ExtraShotOnHit bool
+}
+
+// Rulesets are the presets offered on the start form. The first one
+// is the default.
+var Rulesets = []Ruleset{
+	{
+		Name:  "standard",
+		Label: "Standard",
+		Size:  10,
+		Ships: []ShipClass{
+			{"battleship", "btlship", 5},
+			{"cruiser", "cruiser", 4},
+			{"frigate", "frigate", 3},
+			{"destroyer", "destroyer", 3},
+			{"patrolboat", "patrolboat", 2},
+		},
+	},
+	{
+		Name:  "classic",
+		Label: "Classic (Hasbro)",
+		Size:  10,
+		Ships: []ShipClass{
+			{"carrier", "carrier", 5},
+			{"battleship", "battleship", 4},
+			{"cruiser", "cruiser", 3},
+			{"submarine", "submarine", 3},
+			{"destroyer", "destroyer", 2},
+		},
+	},
+	{
+		Name:  "small",
+		Label: "Small fleet quick game",
+		Size:  8,
+		Ships: []ShipClass{
+			{"cruiser", "cruiser", 3},
+			{"destroyer", "destroyer", 2},
+			{"patrolboat", "patrolboat", 2},
+		},
+		ExtraShotOnHit: true,
+	},
+}
+
+// LookupRuleset returns the preset with the given name, or the
+// default preset if there is no such preset
+func LookupRuleset(name string) Ruleset {
+	for _, rules := range Rulesets {
+		if rules.Name == name {
+			return rules
+		}
+	}
+	return Rulesets[0]
+}
+
+// Fields returns the form fields of the ships
+func (rules Ruleset) Fields() []string {
+	fields := make([]string, len(rules.Ships))
+	for i, ship := range rules.Ships {
+		fields[i] = ship.Field
+	}
+	return fields
+}
+
+// Placeholders gives an example placement for each ship, one ship
+// on every other row, keyed by the ship's form field
+func (rules Ruleset) Placeholders() map[string]string {
+	placeholders := map[string]string{}
+	for i, ship := range rules.Ships {
+		posns := make([]string, ship.Size)
+		for j := range posns {
+			posns[j] = Coord{Row: (2 * i) % rules.Size, Col: j}.String()
+		}
+		placeholders[ship.Field] = strings.Join(posns, ",")
+	}
+	return placeholders
+}
+
+// ErrFleetDoesNotFit is returned when no room for a fleet could be
+// found on the board of its ruleset
+var ErrFleetDoesNotFit = errors.New("models: fleet does not fit on the board")
+
+// placeAttempts is how many times RandomFleet tries to place a ship,
+// and to place the whole fleet once a ship could not be placed
+const placeAttempts = 100
+
+// RandomFleet places the ships of the ruleset at random, horizontally
+// or vertically and without overlaps, and returns the placements as
+// the new game form would have them, e.g. "23,24,25,26" for a ship of
+// 4 squares. It returns ErrFleetDoesNotFit if it finds no room for the
+// fleet, as when the board is not one of BoardSizes.
+func RandomFleet(rules Ruleset) (url.Values, error) {
+	if rules.Size < 1 {
+		return nil, ErrFleetDoesNotFit
+	}
+	for i := 0; i < placeAttempts; i++ {
+		values, ok := placeFleet(rules)
+		if ok {
+			return values, nil
+		}
+	}
+	return nil, ErrFleetDoesNotFit
+}
+
+// placeFleet makes one attempt of RandomFleet, and reports whether
+// each ship found room
+func placeFleet(rules Ruleset) (url.Values, bool) {
+	size := rules.Size
+	used := map[Coord]bool{}
+	values := url.Values{}
+	for _, ship := range rules.Ships {
+		placed := false
+		for i := 0; i < placeAttempts && !placed; i++ {
+			horiz := rand.Intn(2) == 0
+			row, col := rand.Intn(size), rand.Intn(size)
+			if horiz && col+ship.Size > size || !horiz && row+ship.Size > size {
+				continue
+			}
+			squares := make([]Coord, ship.Size)
+			free := true
+			for i := range squares {
+				if horiz {
+					squares[i] = Coord{row, col + i}
+				} else {
+					squares[i] = Coord{row + i, col}
+				}
+				if used[squares[i]] {
+					free = false
+				}
+			}
+			if !free {
+				continue
+			}
+			posns := make([]string, ship.Size)
+			for i, square := range squares {
+				used[square] = true
+				posns[i] = square.String()
+			}
+			values.Set(ship.Field, strings.Join(posns, ","))
+			placed = true
+		}
+		if !placed {
+			return nil, false
+		}
+	}
+	return values, true
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRandomFleet(t *testing.T) {
+	for _, preset := range Rulesets {
+		for _, size := range BoardSizes {
+			rules := preset
+			rules.Size = size
+			t.Run(fmt.Sprintf("%s/%d", rules.Name, size), func(t *testing.T) {
+				fleet, err := RandomFleet(rules)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(fleet) != len(rules.Ships) {
+					t.Errorf("got %d fields; want %d", len(fleet), len(rules.Ships))
+				}
+				used := map[Coord]bool{}
+				for _, ship := range rules.Ships {
+					squares, err := ParseCoords(fleet.Get(ship.Field))
+					if err != nil {
+						t.Fatalf("%s: %v", ship.Name, err)
+					}
+					if len(squares) != ship.Size {
+						t.Errorf("%s: got %d squares; want %d", ship.Name, len(squares), ship.Size)
+					}
+					for i, square := range squares {
+						if !square.On(size) {
+							t.Errorf("%s: %s is off the board", ship.Name, square)
+						}
+						if used[square] {
+							t.Errorf("%s: %s overlaps another ship", ship.Name, square)
+						}
+						used[square] = true
+						if i == 0 {
+							continue
+						}
+						prev := squares[i-1]
+						horiz := square.Row == prev.Row && square.Col == prev.Col+1
+						vert := square.Col == prev.Col && square.Row == prev.Row+1
+						if !horiz && !vert {
+							t.Errorf("%s: %s does not follow %s", ship.Name, square, prev)
+						}
+					}
+				}
+				if _, err := NewPlayer(fleet, rules); err != nil {
+					t.Errorf("NewPlayer refused the fleet: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestRandomFleetDoesNotFit(t *testing.T) {
+	for _, size := range []int{-1, 0, 3} {
+		rules := Rulesets[0]
+		rules.Size = size
+		if _, err := RandomFleet(rules); err != ErrFleetDoesNotFit {
+			t.Errorf("size %d: got %v; want ErrFleetDoesNotFit", size, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoStats is returned by a StatsStore for an account with no
+// recorded games
+var ErrNoStats = errors.New("models: no statistics for user")
+
+// InitialRating is the rating of a player before any rated game
+const InitialRating = 1200
+
+// eloK is the most a rating moves with one game
+const eloK = 32
+
+// Stats are the statistics of the games of an account against other
+// human players
+type Stats struct {
+	UserID    string
+	Name      string
+	Wins      int
+	Losses    int
+	Shots     int // shots fired
+	Hits      int // shots that hit a ship
+	WinTurns  int // shots fired in the games won
+	ShipsLost int
+	Rating    int // Elo rating, moved by ranked games
+	Rated     int // number of ranked games
+}
+
+// NewStats makes the statistics of an account with no games
+func NewStats(userID, name string) *Stats {
+	return &Stats{UserID: userID, Name: name, Rating: InitialRating}
+}
+
+// Games is the number of games played
+func (s *Stats) Games() int {
+	return s.Wins + s.Losses
+}
+
+// Accuracy is the percentage of shots that hit a ship
+func (s *Stats) Accuracy() float64 {
+	if s.Shots == 0 {
+		return 0
+	}
+	return 100 * float64(s.Hits) / float64(s.Shots)
+}
+
+// TurnsToWin is the average number of shots fired in the games won
+func (s *Stats) TurnsToWin() float64 {
+	if s.Wins == 0 {
+		return 0
+	}
+	return float64(s.WinTurns) / float64(s.Wins)
+}
+
+// Result is what an ended game adds to the statistics of its players.
+// Players[0] won.
+type Result struct {
+	GameID  string
+	Players [2]PlayerResult
+	Ranked  bool
+}
+
+// PlayerResult is the part of a Result about one player
+type PlayerResult struct {
+	UserID    string // empty for a guest, whose games are not recorded
+	Name      string
+	Shots     int
+	Hits      int
+	ShipsLost int
+}
+
+// Result gives what the ended game adds to the statistics of its
+// players, or nil if there is nothing to record: the game has not
+// ended, neither player has an account, one is the computer, or both
+// played from the same account.
+// The caller must hold g.Mu.
+func (g *Game) Result() *Result {
+	if g.Status != GameEnded || len(g.Players) != 2 {
+		return nil
+	}
+	r := &Result{GameID: g.ID}
+	accounts := 0
+	for _, pplayer := range g.Players {
+		if pplayer.Bot {
+			return nil
+		}
+		if pplayer.UserID != "" {
+			accounts++
+		}
+		popponent, ok := g.Players[pplayer.OpponentID]
+		if !ok {
+			return nil
+		}
+		pr := PlayerResult{UserID: pplayer.UserID, Name: pplayer.NickName}
+		// the player whose opponent forfeited won, whatever the shots
+		won := g.Forfeited != "" && g.Forfeited != pplayer.ID
+		for _, shot := range pplayer.Shots {
+			pr.Shots++
+			if shot.Outcome != Miss {
+				pr.Hits++
+			}
+			won = won || shot.GameOver
+		}
+		for _, shot := range popponent.Shots {
+			if shot.Outcome == Sunk {
+				pr.ShipsLost++
+			}
+		}
+		if won {
+			r.Players[0] = pr
+		} else {
+			r.Players[1] = pr
+		}
+	}
+	if accounts == 0 {
+		return nil
+	}
+	if r.Players[0].UserID != "" && r.Players[0].UserID == r.Players[1].UserID {
+		return nil
+	}
+	// both players of a ranked game have accounts
+	r.Ranked = g.Ranked && accounts == 2
+	return r
+}
+
+// Apply adds the result to the statistics of the players with
+// accounts, which are in stats by user ID. Missing statistics are
+// made with NewStats.
+func (r *Result) Apply(stats map[string]*Stats) {
+	for i, pr := range r.Players {
+		if pr.UserID == "" {
+			continue
+		}
+		s, ok := stats[pr.UserID]
+		if !ok {
+			s = NewStats(pr.UserID, pr.Name)
+			stats[pr.UserID] = s
+		}
+		s.Shots += pr.Shots
+		s.Hits += pr.Hits
+		s.ShipsLost += pr.ShipsLost
+		if i == 0 {
+			s.Wins++
+			s.WinTurns += pr.Shots
+		} else {
+			s.Losses++
+		}
+	}
+	if r.Ranked {
+		winner, loser := stats[r.Players[0].UserID], stats[r.Players[1].UserID]
+		winner.Rating, loser.Rating = elo(winner.Rating, loser.Rating)
+		winner.Rated++
+		loser.Rated++
+	}
+}
+
+// elo gives the new Elo ratings of the winner and the loser of a game
+func elo(winner, loser int) (int, int) {
+	expected := 1 / (1 + math.Pow(10, float64(loser-winner)/400))
+	delta := int(math.Round(eloK * (1 - expected)))
+	return winner + delta, loser - delta
+}
+
+// StatsStore is implemented by the stores that keep statistics.
+// Record applies a result to the statistics of both its players at
+// once, so they are never seen or saved half recorded. It records the
+// result of a game only once: a result whose GameID was recorded
+// before is ignored, so recording may be retried until it succeeds.
+// Get and List return copies. Get returns ErrNoStats for an account
+// with no games.
+type StatsStore interface {
+	Get(userID string) (*Stats, error)
+	List() []*Stats
+	Record(r *Result) error
+}
@@ -0,0 +1,119 @@
+package models
+
+import "testing"
+
+func TestElo(t *testing.T) {
+	tests := []struct {
+		name          string
+		winner, loser int
+		wantW, wantL  int
+	}{
+		{"equal ratings", 1200, 1200, 1216, 1184},
+		{"favourite wins", 1400, 1200, 1408, 1192},
+		{"underdog wins", 1200, 1400, 1224, 1376},
+		{"far favourite wins", 2000, 1200, 2000, 1200},
+		{"far underdog wins", 1200, 2000, 1232, 1968},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, l := elo(tt.winner, tt.loser)
+			if w != tt.wantW || l != tt.wantL {
+				t.Errorf("elo(%d, %d) = %d, %d; want %d, %d", tt.winner, tt.loser, w, l, tt.wantW, tt.wantL)
+			}
+			if w-tt.winner != tt.loser-l {
+				t.Errorf("the winner gained %d and the loser lost %d", w-tt.winner, tt.loser-l)
+			}
+			if w-tt.winner < 0 || w-tt.winner > eloK {
+				t.Errorf("the rating moved by %d", w-tt.winner)
+			}
+		})
+	}
+}
+
+func TestResultApply(t *testing.T) {
+	r := &Result{
+		GameID: "g1",
+		Players: [2]PlayerResult{
+			{UserID: "u1", Name: "alice", Shots: 20, Hits: 17, ShipsLost: 2},
+			{UserID: "u2", Name: "bobby", Shots: 19, Hits: 10, ShipsLost: 5},
+		},
+		Ranked: true,
+	}
+	stats := map[string]*Stats{"u2": {UserID: "u2", Name: "bobby", Wins: 1, Shots: 30, Hits: 17, WinTurns: 30, Rating: 1200, Rated: 1}}
+	r.Apply(stats)
+
+	alice, bobby := stats["u1"], stats["u2"]
+	if alice == nil {
+		t.Fatal("no statistics made for alice")
+	}
+	if alice.Wins != 1 || alice.Losses != 0 || alice.Shots != 20 || alice.Hits != 17 || alice.WinTurns != 20 || alice.ShipsLost != 2 {
+		t.Errorf("got %+v for alice", alice)
+	}
+	if bobby.Wins != 1 || bobby.Losses != 1 || bobby.Shots != 49 || bobby.Hits != 27 || bobby.WinTurns != 30 || bobby.ShipsLost != 5 {
+		t.Errorf("got %+v for bobby", bobby)
+	}
+	if alice.Rating != 1216 || bobby.Rating != 1184 || alice.Rated != 1 || bobby.Rated != 2 {
+		t.Errorf("got ratings %d (%d games) and %d (%d games)", alice.Rating, alice.Rated, bobby.Rating, bobby.Rated)
+	}
+
+	// an unranked game moves no rating, and a guest has no statistics
+	r = &Result{GameID: "g2", Players: [2]PlayerResult{{Name: "guest", Shots: 17, Hits: 17}, {UserID: "u1", Name: "alice", Shots: 16, Hits: 8}}}
+	r.Apply(stats)
+	if alice.Losses != 1 || alice.Rating != 1216 || alice.Rated != 1 {
+		t.Errorf("got %+v for alice after an unranked loss", alice)
+	}
+	if len(stats) != 2 {
+		t.Errorf("got statistics for %d accounts; want 2", len(stats))
+	}
+}
+
+func TestResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		users    [2]string // accounts of alice and bobby
+		ranked   bool
+		want     bool // a result to record
+		wantRank bool
+	}{
+		{"two accounts, ranked", [2]string{"u1", "u2"}, true, true, true},
+		{"two accounts", [2]string{"u1", "u2"}, false, true, false},
+		{"account and guest, ranked", [2]string{"u1", ""}, true, true, false},
+		{"two guests", [2]string{"", ""}, false, false, false},
+		{"same account, ranked", [2]string{"u1", "u1"}, true, false, false},
+		{"same account", [2]string{"u1", "u1"}, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pgame, p1, p2 := duelGame(t)
+			p1.UserID, p2.UserID = tt.users[0], tt.users[1]
+			pgame.Ranked = tt.ranked
+			if r := pgame.Result(); r != nil {
+				t.Fatalf("got a result for a game in progress: %+v", r)
+			}
+			for _, shot := range []struct {
+				player *Player
+				pos    Coord
+			}{{p1, Coord{5, 5}}, {p2, Coord{7, 7}}, {p1, Coord{6, 5}}} {
+				if _, err := pgame.Fire(shot.player.ID, shot.pos); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := pgame.Result()
+			if (r != nil) != tt.want {
+				t.Fatalf("got result %+v; want one: %t", r, tt.want)
+			}
+			if r == nil {
+				return
+			}
+			if r.Ranked != tt.wantRank {
+				t.Errorf("got Ranked %t; want %t", r.Ranked, tt.wantRank)
+			}
+			if r.Players[0].Name != "alice" || r.Players[0].Shots != 2 || r.Players[0].Hits != 2 || r.Players[1].ShipsLost != 1 {
+				t.Errorf("got %+v; want alice to win with 2 hits", r.Players)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned for accounts
+var (
+	ErrDuplicateName      = errors.New("models: user name already taken")
+	ErrInvalidCredentials = errors.New("models: invalid credentials")
+	ErrNoUser             = errors.New("models: no such user")
+)
+
+// User is the account of a registered player. Guests play without one.
+type User struct {
+	ID             string
+	Name           string // also the player's nickname in games
+	HashedPassword []byte
+	Created        time.Time
+}
+
+// NewUser makes an account with a bcrypt hash of the password
+func NewUser(name, password string) (*User, error) {
+	id, err := fakeUUID()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		ID:             id,
+		Name:           name,
+		HashedPassword: hash,
+		Created:        time.Now(),
+	}, nil
+}
+
+// Authenticate checks the password of the account,
+// returning ErrInvalidCredentials if it is wrong
+func (u *User) Authenticate(password string) error {
+	err := bcrypt.CompareHashAndPassword(u.HashedPassword, []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// UserStore is implemented by the stores that keep accounts. Names
+// are unique regardless of case: Insert returns ErrDuplicateName for
+// a name that differs from a taken one only in case.
+type UserStore interface {
+	Get(id string) (*User, error)
+	GetByName(name string) (*User, error)
+	Insert(u *User) error
+}
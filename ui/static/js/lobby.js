@@ -0,0 +1,87 @@
+window.addEventListener('load', onLoad);
+
+function onLoad () {
+  connect();
+  // the ages of the games go on growing between events
+  setInterval(showAges, 30000);
+}
+
+let es;
+const lobby = document.querySelector('.lobby');
+// number of the last lobby event shown on the page
+let seq = lobby.dataset.seq;
+
+function connect() {
+  es = new EventSource(lobby.dataset.stream + '?after=' + seq);
+  es.onmessage = (e) => {
+    const ev = JSON.parse(e.data);
+    if (ev.type === 'reload') {
+      es.close();
+      document.location.reload(true);
+      return;
+    }
+    seq = ev.seq;
+    const row = lobby.querySelector(`tr[data-game="${ev.game_id}"]`);
+    if (row) {
+      row.remove();
+    }
+    if (ev.type === 'opened') {
+      addGame(ev.game);
+    }
+    const empty = !lobby.querySelector('tbody tr');
+    lobby.querySelector('table').hidden = empty;
+    lobby.querySelector('.lobby-empty').hidden = !empty;
+  }
+
+  es.onerror = () => {
+    if (es.readyState === EventSource.CLOSED) {
+      setTimeout(connect, 5000);
+    }
+  }
+}
+
+// addGame adds a row for the game, the newest last
+function addGame(game) {
+  const tr = document.createElement('tr');
+  tr.dataset.game = game.id;
+  tr.dataset.created = Math.floor(Date.parse(game.created) / 1000);
+  const cells = [
+    game.creator + (game.ranked ? ' (ranked)' : ''),
+    game.ruleset,
+    `${game.size} x ${game.size}`,
+    '',
+  ];
+  cells.forEach((text) => {
+    const td = document.createElement('td');
+    td.textContent = text;
+    tr.appendChild(td);
+  });
+  tr.lastChild.className = 'age';
+  const td = document.createElement('td');
+  const a = document.createElement('a');
+  a.href = `/join/${game.id}`;
+  a.textContent = 'Join';
+  td.appendChild(a);
+  tr.appendChild(td);
+  lobby.querySelector('tbody').appendChild(tr);
+  showAges();
+}
+
+// showAges tells how long ago each game was created, like the server
+function showAges() {
+  const now = Date.now() / 1000;
+  lobby.querySelectorAll('tbody tr').forEach((tr) => {
+    if (tr.dataset.created <= 0) {
+      // saved before the creation of games was kept
+      return;
+    }
+    const d = now - tr.dataset.created;
+    let text = 'just now';
+    if (d >= 3600) {
+      text = `${Math.floor(d / 3600)} h ago`;
+    } else if (d >= 60) {
+      text = `${Math.floor(d / 60)} min ago`;
+    }
+    tr.querySelector('.age').textContent = text;
+  });
+}
@@ -0,0 +1,29 @@
+window.addEventListener('load', onLoad);
+
+function onLoad () {
+  connect();
+}
+
+let es;
+const waiting = document.querySelector('.waiting');
+// number of the last event shown on the page
+let seq = waiting.dataset.seq;
+
+function connect() {
+  es = new EventSource(waiting.dataset.stream + '?after=' + seq);
+  es.onmessage = (e) => {
+    const ev = JSON.parse(e.data);
+    if (ev.type === 'reload' || ev.type === 'matched') {
+      // the page moves on to the game once paired
+      es.close();
+      document.location.reload(true);
+    }
+  }
+
+  es.onerror = () => {
+    if (es.readyState === EventSource.CLOSED) {
+      // the page tells the player if it is no longer waiting
+      setTimeout(() => document.location.reload(true), 5000);
+    }
+  }
+}
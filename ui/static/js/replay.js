@@ -0,0 +1,98 @@
+// Steps through the moves of a replay on the ship boards of both
+// players, which the page shows as they were before the first shot.
+
+const moves = JSON.parse(document.getElementById('replay-moves').textContent);
+let step = 0;
+let timer = null;
+
+// the squares as they were before the first shot
+const initial = new Map();
+document.querySelectorAll('.ship-board td[data-square]').forEach((td) => {
+  initial.set(td, td.innerHTML);
+});
+
+// cell returns a square of the board of the player in the given seat
+function cell(seat, square) {
+  return document.querySelector(`.ship-board[data-seat="${seat}"] td[data-square="${square}"]`);
+}
+
+// apply shows a move on the board of the player fired at
+function apply(move, last) {
+  const target = 1 - move.seat;
+  const td = cell(target, move.square);
+  if (move.outcome === 'miss') {
+    td.innerHTML = ' <img src="/static/img/splash.png" width="32" height="32"> ';
+  } else {
+    const img = td.querySelector('img');
+    img.src = img.src.replace(/\.png$/, '_fire.png');
+  }
+  (move.wreck || []).forEach((square) => {
+    cell(target, square).classList.add('sunk');
+  });
+  if (last) {
+    td.classList.add('last-shot');
+  }
+}
+
+function show(n) {
+  step = Math.max(0, Math.min(n, moves.length));
+  initial.forEach((html, td) => {
+    td.innerHTML = html;
+    td.classList.remove('sunk', 'last-shot');
+  });
+  for (let i = 0; i < step; i++) {
+    apply(moves[i], i === step - 1);
+  }
+
+  document.querySelector('.replay-position').textContent = `Move ${step} of ${moves.length}`;
+  let status = 'Before the first shot.';
+  if (step > 0) {
+    const move = moves[step - 1];
+    status = `${move.by} fired at ${move.square}: ${move.outcome}` + (move.class ? ` (${move.class})` : '') + '.';
+    if (move.game_over) {
+      status += ` ${move.by} has won the game.`;
+    }
+  }
+  document.querySelector('.replay-status').textContent = status;
+  document.querySelectorAll('.move-log li').forEach((li) => {
+    li.classList.toggle('current', li.value === step);
+  });
+}
+
+function pause() {
+  clearInterval(timer);
+  timer = null;
+  document.querySelector('[data-step="play"]').textContent = 'Play';
+}
+
+function play() {
+  if (step === moves.length) {
+    show(0);
+  }
+  document.querySelector('[data-step="play"]').textContent = 'Pause';
+  timer = setInterval(() => {
+    show(step + 1);
+    if (step === moves.length) {
+      pause();
+    }
+  }, 1000);
+}
+
+document.querySelectorAll('[data-step]').forEach((button) => {
+  button.addEventListener('click', () => {
+    const action = button.dataset.step;
+    if (action === 'play') {
+      timer ? pause() : play();
+      return;
+    }
+    pause();
+    switch (action) {
+      case 'first': show(0); break;
+      case 'back': show(step - 1); break;
+      case 'forward': show(step + 1); break;
+      case 'last': show(moves.length); break;
+    }
+  });
+});
+
+show(0);
@@ -2,41 +2,265 @@ window.addEventListener('load', onLoad);
 
 function onLoad () { 
   connect();
+  startClock();
 }
 
 let es;
+const boards = document.querySelector('[data-seq]');
+// number of the last game event shown on the page
+let seq = boards.dataset.seq;
+// the page of a spectator shows the shots boards of both players
+const watching = 'watch' in boards.dataset;
 
 function connect() {
   console.log("connecting");
-  //es = new EventSource('/btlship/sse');
-  es = new EventSource('/sse');
+  //es = new EventSource('/btlship' + boards.dataset.stream + '?after=' + seq);
+  es = new EventSource(boards.dataset.stream + '?after=' + seq);
   es.onmessage = (e) => {
-    if (e.data.includes('refresh')) {
+    const ev = JSON.parse(e.data);
+    if (ev.type === 'reload') {
+      es.close();
+      document.location.reload(true);
+      return;
+    }
+    if (ev.type === 'expired') {
+      // the game is removed from the server, and the page can no
+      // longer be played
+      es.close();
+      showMessages(['This game has expired after a long time without a move. Start a new game.']);
+      document.querySelectorAll('.form-container, .resign, .chat-form').forEach((el) => {
+        el.hidden = true;
+      });
+      return;
+    }
+    seq = ev.seq;
+    switch (ev.type) {
+      case 'joined':
+        if (watching) {
+          // show the shots board of the new player
+          es.close();
+          document.location.reload(true);
+          return;
+        }
+        document.querySelectorAll('.opponent').forEach((span) => {
+          span.textContent = ev.by;
+        });
+        // a game that has started is no longer in the lobby,
+        // and its players may resign
+        document.querySelectorAll('.private').forEach((section) => {
+          section.hidden = true;
+        });
+        document.querySelectorAll('.resign').forEach((section) => {
+          section.hidden = false;
+        });
+        break;
+      case 'shot':
+        showShot(ev);
+        break;
+      case 'sunk':
+        showSunk(ev);
+        break;
+      case 'chat':
+        if (!watching) {
+          showChat(ev);
+        }
+        break;
+      case 'muted':
+        document.querySelectorAll('.chat-muted').forEach((p) => {
+          p.hidden = !ev.muted;
+        });
+        document.querySelectorAll('.chat-form').forEach((form) => {
+          form.hidden = ev.muted;
+        });
+        break;
+      case 'spectators':
+        document.querySelectorAll('.watching').forEach((span) => {
+          span.textContent = ev.count;
+        });
+        break;
+    }
+    if (ev.type === 'rematch' || (ev.type === 'left' && !watching)) {
+      // the page of the ended game tells who wants a rematch or has
+      // left, and moves on to the rematch once both players want one
+      es.close();
+      document.location.reload(true);
+      return;
+    }
+    if (watching) {
+      if (ev.type === 'gameover') {
+        // the stream stays open in case the players have a rematch
+        showMessages([`${ev.by} has won the game.`]);
+      }
+      if (ev.type === 'forfeit') {
+        showMessages([forfeitMessage(ev)]);
+      }
+      if (ev.type === 'away') {
+        showMessages([`${ev.by} has disconnected.`]);
+      }
+      if (ev.type === 'back') {
+        showMessages([`${ev.by} is back.`]);
+      }
+      return;
+    }
+    showStatus(ev);
+    if (ev.clock) {
+      setClock(ev.clock, ev.your_turn);
+    }
+    if (ev.type === 'gameover' || ev.type === 'forfeit') {
+      // the final page offers a rematch
       es.close();
       document.location.reload(true);
     }
   }
 
+  // EventSource reconnects by itself unless the server refused the stream
   es.onerror = () => {
     console.log("error");
-    connect();
+    if (es.readyState === EventSource.CLOSED) {
+      // spectators are refused once the owner stops them,
+      // and the page tells them so when reloaded
+      setTimeout(watching ? () => document.location.reload(true) : connect, 5000);
+    }
   }
 }
 
-/*
-function connect() {
-  //gotActivity();
-  let es = new EventSource('/sse');
-  es.onmessage = (e) => {
-    if (e.data.includes('refresh')) {
-      es.close();
-      document.location.reload(true);
+// forfeitMessage tells spectators why a player lost the game
+function forfeitMessage(ev) {
+  switch (ev.text) {
+    case 'time':
+      return `${ev.by} ran out of time.`;
+    case 'resigned':
+      return `${ev.by} resigned.`;
+    case 'abandoned':
+      return `${ev.by} did not come back.`;
+  }
+  return `${ev.by} has forfeited the game.`;
+}
+
+// cell returns a square of one of the boards of the page
+function cell(board, square) {
+  return document.querySelector(`${board} td[data-square="${square}"]`);
+}
+
+// shotsBoard gives the board showing the shots of the player of the event
+function shotsBoard(ev) {
+  return watching ? `.shots-board[data-seat="${ev.seat}"]` : '.shots-board';
+}
+
+function showShot(ev) {
+  if (ev.mine || watching) {
+    const td = cell(shotsBoard(ev), ev.square);
+    const img = ev.outcome === 'miss' ? 'splash' : 'hit_bomb';
+    td.innerHTML = ` <img src="/static/img/${img}.png" width="32" height="32"> `;
+  } else if (ev.outcome !== 'miss') {
+    const img = cell('.ship-board', ev.square).querySelector('img');
+    if (img && !img.src.endsWith('_fire.png')) {
+      img.src = img.src.replace(/\.png$/, '_fire.png');
     }
   }
 
-  es.onerror = () => {
-    console.log("error");
-    es = new EventSource('/sse');
+  const log = document.querySelector('.move-log');
+  if (log.querySelector(`li[value="${ev.turn}"]`)) {
+    return;
+  }
+  const li = document.createElement('li');
+  li.value = ev.turn;
+  li.textContent = `${ev.time} ${ev.by} fired at ${ev.square}: ${ev.outcome}` + (ev.class ? ` (${ev.class})` : '');
+  log.querySelector('ol').appendChild(li);
+  log.hidden = false;
+}
+
+function showSunk(ev) {
+  const board = ev.mine || watching ? shotsBoard(ev) : '.ship-board';
+  ev.wreck.forEach((square) => {
+    cell(board, square).classList.add('sunk');
+  });
+}
+
+// showChat adds a chat message to the log. The text is set as text,
+// never as HTML.
+function showChat(ev) {
+  const log = document.querySelector('.chat-log');
+  if (!log) {
+    return;
+  }
+  const li = document.createElement('li');
+  if (ev.mine) {
+    li.className = 'mine';
+  }
+  const b = document.createElement('b');
+  b.textContent = ev.by;
+  li.append(`${ev.time} `, b, `: ${ev.text}`);
+  log.appendChild(li);
+  log.scrollTop = log.scrollHeight;
+}
+
+function showStatus(ev) {
+  showMessages(ev.messages);
+  const form = document.querySelector('.form-container');
+  if (form) {
+    form.hidden = !ev.your_turn;
+  }
+}
+
+function showMessages(messages) {
+  const ul = document.querySelector('.status-msg');
+  ul.innerHTML = '';
+  messages.forEach((msg) => {
+    const li = document.createElement('li');
+    li.textContent = msg;
+    ul.appendChild(li);
+  });
+}
+
+// the clocks as last told by the server, and when they were told
+let clockState;
+
+// startClock counts down the clocks shown on the page, if any
+function startClock() {
+  const section = document.querySelector('.clock');
+  if (!section) {
+    return;
+  }
+  // the clocks run once the game is in progress
+  if (!section.querySelector('.clock-running').hidden) {
+    setClock({
+      left: Number(section.dataset.left),
+      mine: Number(section.dataset.mine),
+      theirs: Number(section.dataset.theirs),
+    }, 'yourTurn' in section.dataset);
+  }
+  setInterval(showClock, 250);
+}
+
+function setClock(clock, yourTurn) {
+  clockState = {clock: clock, yourTurn: yourTurn, at: Date.now()};
+  document.querySelectorAll('.clock-running').forEach((div) => {
+    div.hidden = false;
+  });
+  showClock();
+}
+
+// showClock shows the clocks as they are now: the time to fire and the
+// game clock of the player to play run down, the other clock stands
+function showClock() {
+  if (!clockState) {
+    return;
   }
+  const elapsed = Date.now() - clockState.at;
+  const c = clockState.clock;
+  const show = (selector, ms) => {
+    document.querySelectorAll(`.clock ${selector}`).forEach((span) => {
+      span.textContent = formatClock(ms);
+    });
+  };
+  show('.left', c.left - elapsed);
+  show('.mine', clockState.yourTurn ? c.mine - elapsed : c.mine);
+  show('.theirs', clockState.yourTurn ? c.theirs : c.theirs - elapsed);
+}
+
+// formatClock shows milliseconds as minutes and seconds, like the server
+function formatClock(ms) {
+  const seconds = Math.max(0, Math.ceil(ms / 1000));
+  return `${Math.floor(seconds / 60)}:${String(seconds % 60).padStart(2, '0')}`;
 }
-*/
\ No newline at end of file